@@ -0,0 +1,689 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default values for the `agent` command.
+const (
+	DefaultAgentListen   = "127.0.0.1:8210"
+	DefaultAgentInterval = "1m"
+	DefaultAgentJitter   = "10s"
+	// DefaultAgentMaxAttempts is the number of consecutive non-OK results
+	// before a check goes into a hard state.
+	DefaultAgentMaxAttempts = 3
+	// DefaultAgentFlapLowThreshold and DefaultAgentFlapHighThreshold are the
+	// percent state changes below which a check stops flapping and above
+	// which it starts flapping.
+	DefaultAgentFlapLowThreshold  = 20.0
+	DefaultAgentFlapHighThreshold = 30.0
+)
+
+const (
+	agentListenDescr   = "Address the HTTP API listens on (default: %s)"
+	agentIntervalDescr = "Interval of the checks without an interval of their own (default: %s)"
+	agentJitterDescr   = "Maximum random delay added to the interval of the checks (default: %s)"
+
+	agentMaxAttemptsDescr = "Number of consecutive non-OK results before a check goes into " +
+		"a hard state (default: %d)"
+	agentFlapLowDescr  = "Percent state change below which a check stops flapping (default: %g)"
+	agentFlapHighDescr = "Percent state change above which a check starts flapping, " +
+		"0 disables the flap detection (default: %g)"
+	agentStateFileDescr = "File where the state history of the checks is saved across restarts"
+)
+
+// agentHistorySize is the number of states of a check kept in its history
+// and used for the flap detection.
+const agentHistorySize = 21
+
+// agentShutdownTimeout is the time given to the HTTP API for completing the
+// pending requests when the agent is stopped.
+const agentShutdownTimeout = 5 * time.Second
+
+// agentStateHeader is the HTTP header holding the hard state of a check when
+// its result is served in the Nagios plugin format.
+const agentStateHeader = "X-Check-State"
+
+// AgentCommand is a CLI Command that holds the attributes of the command `agent`.
+type AgentCommand struct {
+	*BaseCommand
+	Listen      string
+	Interval    string
+	Jitter      string
+	MaxAttempts int
+	FlapLow     float64
+	FlapHigh    float64
+	StateFile   string
+}
+
+// agentResult is the last result of a check run by the agent, along with
+// its state history, as returned by the HTTP API and saved in the state file.
+type agentResult struct {
+	Name      string     `json:"name"`
+	Command   string     `json:"command"`
+	Interval  string     `json:"interval"`
+	State     int        `json:"state"`
+	StateName string     `json:"state_name"`
+	Output    string     `json:"output"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	Duration  float64    `json:"duration_seconds"`
+	NextCheck *time.Time `json:"next_check,omitempty"`
+
+	StateType      string         `json:"state_type"`
+	Attempt        int            `json:"attempt"`
+	MaxAttempts    int            `json:"max_attempts"`
+	HardState      int            `json:"hard_state"`
+	HardStateName  string         `json:"hard_state_name"`
+	LastHardChange *time.Time     `json:"last_hard_state_change,omitempty"`
+	Flapping       bool           `json:"flapping"`
+	PercentChange  float64        `json:"percent_state_change"`
+	History        []agentHistory `json:"history,omitempty"`
+}
+
+// agentHistory is a state of a check in its history.
+type agentHistory struct {
+	Time  time.Time `json:"time"`
+	State int       `json:"state"`
+}
+
+// agentOptions holds the settings of the agent scheduler.
+type agentOptions struct {
+	// interval is the interval of the checks without an interval of their own.
+	interval time.Duration
+	// jitter is the maximum random delay added to the intervals.
+	jitter time.Duration
+	// maxAttempts is the number of consecutive non-OK results before a
+	// check goes into a hard state.
+	maxAttempts int
+	// flapLow and flapHigh are the flap detection thresholds, in percent
+	// state change. A zero flapHigh disables the flap detection.
+	flapLow, flapHigh float64
+	// stateFile, if not empty, is the file the results are saved to.
+	stateFile string
+}
+
+// agentScheduler runs every check on its own interval and keeps the last
+// result of each one.
+type agentScheduler struct {
+	checks []batchCheck
+	opts   agentOptions
+	run    func(check batchCheck) (int, string)
+	log    func(message string)
+
+	mu      sync.RWMutex
+	results map[string]*agentResult
+}
+
+// newAgentScheduler returns the scheduler of the given checks, run by the
+// function run. The state changes are reported to the function log.
+func newAgentScheduler(checks []batchCheck, opts agentOptions,
+	run func(check batchCheck) (int, string), log func(message string)) *agentScheduler {
+	if opts.maxAttempts < 1 {
+		opts.maxAttempts = 1
+	}
+	s := &agentScheduler{
+		opts:    opts,
+		run:     run,
+		log:     log,
+		results: make(map[string]*agentResult),
+	}
+	for _, check := range checks {
+		if check.Interval == 0 {
+			check.Interval = opts.interval
+		}
+		s.checks = append(s.checks, check)
+		s.results[check.Name] = &agentResult{
+			Name:          check.Name,
+			Command:       check.Command,
+			Interval:      check.Interval.String(),
+			State:         StateUndefined,
+			StateName:     stateLabels[StateUndefined],
+			Output:        "the check has not been run yet",
+			StateType:     "soft",
+			MaxAttempts:   opts.maxAttempts,
+			HardState:     StateOk,
+			HardStateName: stateLabels[StateOk],
+		}
+	}
+	return s
+}
+
+// restore restores the state history of the checks from the results saved
+// in the state file. The results of the checks no longer listed, or whose
+// command changed, are discarded.
+func (s *agentScheduler) restore(saved []agentResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, previous := range saved {
+		r, ok := s.results[previous.Name]
+		if !ok || r.Command != previous.Command {
+			continue
+		}
+		previous.Interval = r.Interval
+		previous.MaxAttempts = r.MaxAttempts
+		previous.NextCheck = nil
+		*r = previous
+	}
+}
+
+// delay returns a random delay between zero and the jitter, spreading the
+// runs of the checks over time.
+func (s *agentScheduler) delay() time.Duration {
+	if s.opts.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.opts.jitter)))
+}
+
+// record updates the result r with the state of a new run of the check.
+// A non-OK state is soft until it has been returned by maxAttempts
+// consecutive runs, unless the check is already in a non-OK hard state; an
+// OK state is always hard. The check is flapping when its percent state
+// change rises above the high threshold, and until it falls below the low
+// one. It returns whether the state, the hard state and the flapping status
+// changed.
+func (s *agentScheduler) record(r *agentResult, state int, at time.Time) (changed, hardChanged, flapChanged bool) {
+	wasRun := r.LastCheck != nil
+	changed = !wasRun || r.State != state
+
+	switch {
+	case state == StateOk:
+		r.Attempt = 1
+	case wasRun && r.State != StateOk:
+		r.Attempt = min(r.Attempt+1, s.opts.maxAttempts)
+	default:
+		r.Attempt = 1
+	}
+	r.State = state
+	r.StateName = stateLabels[state]
+	r.MaxAttempts = s.opts.maxAttempts
+
+	hasHardState := r.LastHardChange != nil
+	if state == StateOk || r.Attempt >= s.opts.maxAttempts ||
+		(hasHardState && r.HardState != StateOk) {
+		r.StateType = "hard"
+		if !hasHardState || r.HardState != state {
+			hardChanged = true
+			r.HardState = state
+			r.HardStateName = stateLabels[state]
+			r.LastHardChange = &at
+		}
+	} else {
+		r.StateType = "soft"
+	}
+
+	r.History = append(r.History, agentHistory{Time: at, State: state})
+	if len(r.History) > agentHistorySize {
+		r.History = r.History[len(r.History)-agentHistorySize:]
+	}
+	var changes int
+	for i := 1; i < len(r.History); i++ {
+		if r.History[i].State != r.History[i-1].State {
+			changes++
+		}
+	}
+	r.PercentChange = float64(changes) * 100 / (agentHistorySize - 1)
+
+	switch {
+	case !r.Flapping && s.opts.flapHigh > 0 && r.PercentChange >= s.opts.flapHigh:
+		r.Flapping, flapChanged = true, true
+	case r.Flapping && (s.opts.flapHigh <= 0 || r.PercentChange < s.opts.flapLow):
+		r.Flapping, flapChanged = false, true
+	}
+	return changed, hardChanged, flapChanged
+}
+
+// runCheck runs the given check, records its result and saves the results
+// to the state file, if any.
+func (s *agentScheduler) runCheck(check batchCheck) {
+	start := time.Now()
+	code, output := s.run(check)
+	duration := time.Since(start)
+
+	var messages []string
+
+	s.mu.Lock()
+	r := s.results[check.Name]
+	changed, hardChanged, flapChanged := s.record(r, code, start)
+	r.Output = output
+	r.LastCheck = &start
+	r.Duration = duration.Seconds()
+	result := *r
+
+	if s.opts.stateFile != "" {
+		if err := writeStateFile(s.opts.stateFile, s.snapshot()); err != nil {
+			messages = append(messages,
+				fmt.Sprintf("error writing the state file %s: %s", s.opts.stateFile, err))
+		}
+	}
+	s.mu.Unlock()
+
+	if changed || hardChanged {
+		message := check.Name + ": " + output
+		switch {
+		case result.StateType == "soft":
+			message += fmt.Sprintf(" (soft state, attempt %d/%d)", result.Attempt, result.MaxAttempts)
+		case !changed:
+			message += " (hard state)"
+		}
+		messages = append([]string{message}, messages...)
+	}
+	if flapChanged {
+		verb := "stopped"
+		if result.Flapping {
+			verb = "started"
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s flapping (%.0f%% state change)",
+			check.Name, verb, result.PercentChange))
+	}
+	if s.log != nil {
+		for _, message := range messages {
+			s.log(message)
+		}
+	}
+}
+
+// schedule records the time the given check will be run next.
+func (s *agentScheduler) schedule(check batchCheck, next time.Time) {
+	s.mu.Lock()
+	s.results[check.Name].NextCheck = &next
+	s.mu.Unlock()
+}
+
+// start runs the checks until the context is canceled, and waits for the
+// running checks to end.
+func (s *agentScheduler) start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, check := range s.checks {
+		wg.Add(1)
+		go func(check batchCheck) {
+			defer wg.Done()
+
+			wait := s.delay()
+			for {
+				s.schedule(check, time.Now().Add(wait))
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				s.runCheck(check)
+				wait = check.Interval + s.delay()
+			}
+		}(check)
+	}
+	wg.Wait()
+}
+
+// snapshot returns a copy of the results of all the checks, in the order of
+// the checks file. The caller must hold the lock.
+func (s *agentScheduler) snapshot() []agentResult {
+	results := make([]agentResult, 0, len(s.checks))
+	for _, check := range s.checks {
+		r := *s.results[check.Name]
+		r.History = append([]agentHistory(nil), r.History...)
+		results = append(results, r)
+	}
+	return results
+}
+
+// result returns a copy of the last result of the given check.
+func (s *agentScheduler) result(name string) (agentResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.results[name]
+	if !ok {
+		return agentResult{}, false
+	}
+	result := *r
+	result.History = append([]agentHistory(nil), r.History...)
+	return result, true
+}
+
+// writeJSON writes the value v to the HTTP response w.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeNagios writes the output of the result r to the HTTP response w, as it
+// would be printed by a Nagios plugin, with its hard state, the exit code of
+// the plugin, in the agentStateHeader header.
+func writeNagios(w http.ResponseWriter, r agentResult) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(agentStateHeader, strconv.Itoa(r.HardState))
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, r.Output)
+}
+
+// handler returns the HTTP API serving the last results of the checks:
+// GET /checks returns all of them, in the order of the checks file, and
+// GET /checks/<name> the one of the given check, in the plugin format when
+// the query parameter format=nagios is set.
+func (s *agentScheduler) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /checks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		results := s.snapshot()
+		s.mu.RUnlock()
+		writeJSON(w, http.StatusOK, results)
+	})
+	mux.HandleFunc("GET /checks/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		result, ok := s.result(name)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{
+				"error": fmt.Sprintf("no such check: %s", name),
+			})
+			return
+		}
+		switch format := r.URL.Query().Get("format"); format {
+		case "", "json":
+			writeJSON(w, http.StatusOK, result)
+		case "nagios":
+			writeNagios(w, result)
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("unknown format: %s", format),
+			})
+		}
+	})
+	return mux
+}
+
+// serve runs the checks and serves their results on the given listener until
+// the context is canceled.
+func (s *agentScheduler) serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Serve(listener)
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.start(ctx)
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+		cancel()
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), agentShutdownTimeout)
+	defer shutdownCancel()
+	if shutdownErr := server.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	<-done
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Synopsis returns a short synopsis of the `agent` command.
+func (c *AgentCommand) Synopsis() string {
+	return "Run the checks listed in a file periodically and serve their results over HTTP"
+}
+
+// Help returns a long-form help text of the `agent` command.
+func (c *AgentCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor agent [options] FILE
+
+  This command runs in the foreground the checks listed in FILE, each one on
+  its own interval, keeps the last result of every check in memory and
+  serves the results over an HTTP API. The monitoring tools can then fetch
+  the cached results instead of running a check, and establishing a new
+  connection to Vault, every time.
+
+  The file has the format used by the 'batch' command. The name of the
+  service can be followed by the interval of the check, in the form
+  @DURATION:
+
+    # service        interval  command    arguments
+    "Vault status"   @30s      status
+    "Vault HA"       @1m       hastatus   -discover
+    "Vault token"    @1h       token-lookup -warning 240h
+
+    $ hashicorp-vault-monitor agent /etc/vault-monitor/checks
+
+  The HTTP API serves the results in the JSON format:
+
+    GET /checks          the results of all the checks
+    GET /checks/<name>   the result of the given check
+
+  With GET /checks/<name>?format=nagios, the output of the check is returned
+  in plain text instead, as printed by a Nagios plugin, and its hard state in
+  the 'X-Check-State' header.
+
+  As in Nagios, a non-OK state of a check is soft until it has been returned
+  by -max-attempts consecutive runs, and then becomes hard: the hard state
+  (the 'hard_state' field of the results) is the one to alert on, so that a
+  single transient failure does not page anyone. A check whose state changes
+  too often in its last %d runs is flagged as flapping.
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+    -listen=<string>
+       Address the HTTP API listens on (default: %s).
+
+    -interval=<duration>
+       Interval of the checks without an interval of their own (default: %s).
+
+    -jitter=<duration>
+       Maximum random delay added to the interval of the checks, and to the
+       first run of each check, so that the checks do not hit Vault all at
+       the same time (default: %s).
+
+    -max-attempts=<int>
+       Number of consecutive non-OK results before a check goes into a hard
+       state (default: %d).
+
+    -flap-low-threshold=<float>
+       Percent state change below which a flapping check stops flapping
+       (default: %g).
+
+    -flap-high-threshold=<float>
+       Percent state change above which a check starts flapping (default:
+       %g). The percent state change is the number of state changes in the
+       last runs of the check. Set it to 0 to disable the flap detection.
+
+    -state-file=<string>
+       File where the results of the checks and their state history are
+       saved, and restored from when the agent is restarted.
+
+  The agent stops on SIGINT or SIGTERM. The state changes of the checks are
+  logged to the standard output.
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		agentHistorySize,
+		batchOutputFlagsHelp(),
+		DefaultAgentListen, DefaultAgentInterval, DefaultAgentJitter,
+		DefaultAgentMaxAttempts, DefaultAgentFlapLowThreshold, DefaultAgentFlapHighThreshold)
+}
+
+// Run executes the `agent` command with the given CLI instance and command-line arguments.
+func (c *AgentCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("agent", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.Listen, "listen",
+		DefaultAgentListen,
+		fmt.Sprintf(agentListenDescr, DefaultAgentListen))
+	cmdFlags.StringVar(&c.Interval, "interval",
+		DefaultAgentInterval,
+		fmt.Sprintf(agentIntervalDescr, DefaultAgentInterval))
+	cmdFlags.StringVar(&c.Jitter, "jitter",
+		DefaultAgentJitter,
+		fmt.Sprintf(agentJitterDescr, DefaultAgentJitter))
+	cmdFlags.IntVar(&c.MaxAttempts, "max-attempts",
+		DefaultAgentMaxAttempts,
+		fmt.Sprintf(agentMaxAttemptsDescr, DefaultAgentMaxAttempts))
+	cmdFlags.Float64Var(&c.FlapLow, "flap-low-threshold",
+		DefaultAgentFlapLowThreshold,
+		fmt.Sprintf(agentFlapLowDescr, DefaultAgentFlapLowThreshold))
+	cmdFlags.Float64Var(&c.FlapHigh, "flap-high-threshold",
+		DefaultAgentFlapHighThreshold,
+		fmt.Sprintf(agentFlapHighDescr, DefaultAgentFlapHighThreshold))
+	cmdFlags.StringVar(&c.StateFile, "state-file", "", agentStateFileDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	// the results are exported by the checks themselves
+	out, err := c.outputter()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	switch {
+	case len(args) < 1:
+		out.Undefined("Not enough arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	case len(args) > 1:
+		out.Undefined("Too many arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	}
+
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil || interval <= 0 {
+		out.Undefined("invalid interval: %s", c.Interval)
+		return StateUndefined
+	}
+	jitter, err := time.ParseDuration(c.Jitter)
+	if err != nil || jitter < 0 {
+		out.Undefined("invalid jitter: %s", c.Jitter)
+		return StateUndefined
+	}
+	if c.MaxAttempts < 1 {
+		out.Undefined("invalid number of attempts: %d", c.MaxAttempts)
+		return StateUndefined
+	}
+	if c.FlapLow < 0 || c.FlapHigh > 100 || (c.FlapHigh > 0 && c.FlapLow > c.FlapHigh) {
+		out.Undefined("invalid flap thresholds (low: %g, high: %g)", c.FlapLow, c.FlapHigh)
+		return StateUndefined
+	}
+
+	checks, err := loadChecks(args[0])
+	if err != nil {
+		out.Undefined("error reading the checks: %s", err)
+		return StateUndefined
+	}
+	if len(checks) == 0 {
+		out.Undefined("No checks found in %s", args[0])
+		return StateUndefined
+	}
+
+	// the Vault client, and its connections, are shared by all the checks
+	if _, err := c.Client(); err != nil {
+		out.Undefined("error creating the Vault client: %s", err)
+		return StateUndefined
+	}
+
+	var saved []agentResult
+	if c.StateFile != "" {
+		if _, err := readStateFile(c.StateFile, &saved); err != nil {
+			out.Undefined("error reading the state file: %s", err)
+			return StateUndefined
+		}
+	}
+
+	listener, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		out.Undefined("error listening on %s: %s", c.Listen, err)
+		return StateUndefined
+	}
+
+	scheduler := newAgentScheduler(checks, agentOptions{
+		interval:    interval,
+		jitter:      jitter,
+		maxAttempts: c.MaxAttempts,
+		flapLow:     c.FlapLow,
+		flapHigh:    c.FlapHigh,
+		stateFile:   c.StateFile,
+	}, c.runCheck, c.UI.Info)
+	scheduler.restore(saved)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c.UI.Info(fmt.Sprintf("Running %d checks, serving their results on http://%s/checks",
+		len(checks), listener.Addr()))
+	if err := scheduler.serve(ctx, listener); err != nil {
+		c.UI.Error(fmt.Sprintf("error serving the HTTP API: %s", err))
+		return StateUndefined
+	}
+	c.UI.Info("Agent stopped")
+
+	return StateOk
+}
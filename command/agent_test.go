@@ -0,0 +1,504 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func testAgentCommand(t *testing.T) (*cli.MockUi, *AgentCommand) {
+	t.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &AgentCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+// getAgentResult returns the result of the check name served by the agent
+// HTTP API at url.
+func getAgentResult(t *testing.T, url, name string) (int, agentResult) {
+	t.Helper()
+
+	resp, err := http.Get(url + "/checks/" + strings.ReplaceAll(name, " ", "%20"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result agentResult
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+func TestAgentScheduler(t *testing.T) {
+	checks := []batchCheck{
+		{Name: "fast", Interval: 10 * time.Millisecond, Command: "status"},
+		{Name: "slow", Command: "hastatus"},
+	}
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	var logged []string
+
+	run := func(check batchCheck) (int, string) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[check.Name]++
+		if check.Name == "fast" && runs[check.Name] > 2 {
+			return StateCritical, "fast is critical"
+		}
+		return StateOk, check.Name + " is ok"
+	}
+	log := func(message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, message)
+	}
+
+	s := newAgentScheduler(checks, agentOptions{interval: time.Hour}, run, log)
+
+	if r, _ := s.result("slow"); r.Interval != "1h0m0s" || r.State != StateUndefined || r.LastCheck != nil {
+		t.Errorf("unexpected initial result: %+v", r)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.start(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs["fast"] < 5 {
+		t.Errorf("expected the check 'fast' to be run at least 5 times, got %d", runs["fast"])
+	}
+	if runs["slow"] != 1 {
+		t.Errorf("expected the check 'slow' to be run once, got %d", runs["slow"])
+	}
+
+	if r, _ := s.result("fast"); r.State != StateCritical || r.StateName != "CRITICAL" ||
+		r.Output != "fast is critical" || r.LastCheck == nil || r.NextCheck == nil {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r, _ := s.result("slow"); r.State != StateOk || r.NextCheck.Before(time.Now().Add(50*time.Minute)) {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	// only the state changes are logged
+	expected := []string{"fast: fast is ok", "slow: slow is ok", "fast: fast is critical"}
+	if len(logged) != len(expected) {
+		t.Fatalf("expected %q to be %q", logged, expected)
+	}
+	for _, message := range expected {
+		if !contains(logged, message) {
+			t.Errorf("expected %q to contain %q", logged, message)
+		}
+	}
+}
+
+func TestAgentScheduler_Record(t *testing.T) {
+	cases := []struct {
+		name        string
+		states      []int
+		maxAttempts int
+		stateType   string
+		attempt     int
+		hardState   int
+		hardChanges int
+	}{
+		{"ok", []int{StateOk, StateOk}, 3, "hard", 1, StateOk, 1},
+		{"first_failure", []int{StateCritical}, 3, "soft", 1, StateOk, 0},
+		{"first_failures", []int{StateUndefined, StateCritical}, 3, "soft", 2, StateOk, 0},
+		{"first_failures_hard", []int{StateCritical, StateCritical, StateCritical}, 3, "hard", 3, StateCritical, 1},
+		{"transient_failure", []int{StateOk, StateUndefined, StateOk}, 3, "hard", 1, StateOk, 1},
+		{"soft_failure", []int{StateOk, StateCritical, StateCritical}, 3, "soft", 2, StateOk, 1},
+		{"hard_failure", []int{StateOk, StateCritical, StateWarning, StateCritical}, 3, "hard", 3, StateCritical, 2},
+		{"hard_state_change", []int{StateOk, StateCritical, StateCritical, StateWarning}, 2, "hard", 2, StateWarning, 3},
+		{"attempts_capped", []int{StateCritical, StateCritical, StateCritical}, 2, "hard", 2, StateCritical, 1},
+		{"recovery", []int{StateCritical, StateCritical, StateCritical, StateOk}, 3, "hard", 1, StateOk, 2},
+		{"single_attempt", []int{StateOk, StateWarning}, 1, "hard", 1, StateWarning, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newAgentScheduler([]batchCheck{{Name: "test", Command: "status"}},
+				agentOptions{maxAttempts: tc.maxAttempts}, nil, nil)
+			r := s.results["test"]
+
+			var hardChanges int
+			at := time.Now()
+			for _, state := range tc.states {
+				if _, hardChanged, _ := s.record(r, state, at); hardChanged {
+					hardChanges++
+				}
+				r.LastCheck = &at
+				at = at.Add(time.Minute)
+			}
+
+			if r.StateType != tc.stateType || r.Attempt != tc.attempt || r.HardState != tc.hardState ||
+				r.HardStateName != stateLabels[tc.hardState] {
+				t.Errorf("unexpected result: %+v", *r)
+			}
+			if hardChanges != tc.hardChanges {
+				t.Errorf("expected %d hard state changes, got %d", tc.hardChanges, hardChanges)
+			}
+		})
+	}
+}
+
+func TestAgentScheduler_Flapping(t *testing.T) {
+	s := newAgentScheduler([]batchCheck{{Name: "test", Command: "status"}},
+		agentOptions{maxAttempts: 3, flapLow: 20, flapHigh: 30}, nil, nil)
+	r := s.results["test"]
+
+	var started, stopped int
+	at := time.Now()
+	record := func(state int) {
+		_, _, flapChanged := s.record(r, state, at)
+		if flapChanged && r.Flapping {
+			started++
+		} else if flapChanged {
+			stopped++
+		}
+		r.LastCheck = &at
+		at = at.Add(time.Minute)
+	}
+
+	// 5 state changes in the last 21 runs: 25%
+	for _, state := range []int{StateOk, StateUndefined, StateOk, StateUndefined, StateOk, StateUndefined} {
+		record(state)
+	}
+	if r.Flapping || r.PercentChange != 25 {
+		t.Fatalf("expected the check not to be flapping yet: %+v", *r)
+	}
+
+	// 6 state changes: 30%
+	record(StateOk)
+	if !r.Flapping || r.PercentChange != 30 || started != 1 {
+		t.Fatalf("expected the check to be flapping: %+v", *r)
+	}
+	if r.HardState != StateOk {
+		t.Errorf("expected the transient failures not to change the hard state: %+v", *r)
+	}
+
+	// the state changes move out of the history
+	for i := 0; i < agentHistorySize; i++ {
+		record(StateOk)
+		if r.Flapping != (r.PercentChange >= 20) {
+			t.Fatalf("unexpected flapping status at %.0f%%", r.PercentChange)
+		}
+	}
+	if r.Flapping || r.PercentChange != 0 || stopped != 1 || len(r.History) != agentHistorySize {
+		t.Errorf("expected the check to stop flapping: %+v", *r)
+	}
+}
+
+func TestAgentScheduler_StateFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "agent.json")
+	checks := []batchCheck{
+		{Name: "Vault status", Command: "status"},
+		{Name: "Vault HA", Command: "hastatus"},
+	}
+	opts := agentOptions{interval: time.Minute, maxAttempts: 2, stateFile: stateFile}
+	run := func(check batchCheck) (int, string) {
+		return StateCritical, check.Name + " is critical"
+	}
+
+	var logged []string
+	log := func(message string) {
+		logged = append(logged, message)
+	}
+
+	s := newAgentScheduler(checks, opts, run, log)
+	s.runCheck(checks[0])
+
+	var saved []agentResult
+	if found, err := readStateFile(stateFile, &saved); err != nil || !found {
+		t.Fatalf("the state file has not been written: %v", err)
+	}
+	if len(saved) != 2 || saved[0].Attempt != 1 || saved[0].StateType != "soft" {
+		t.Fatalf("unexpected saved results: %+v", saved)
+	}
+
+	// the second failure after a restart makes the state hard
+	checks[1].Command = "raft"
+	s = newAgentScheduler(checks, opts, run, log)
+	s.restore(append(saved, agentResult{Name: "removed", Command: "status"}))
+	if r, _ := s.result("Vault HA"); r.LastCheck != nil {
+		t.Errorf("expected the result of a changed check to be discarded: %+v", r)
+	}
+	if _, ok := s.result("removed"); ok {
+		t.Error("expected the result of a removed check to be discarded")
+	}
+	s.runCheck(checks[0])
+
+	r, _ := s.result("Vault status")
+	if r.StateType != "hard" || r.HardState != StateCritical || r.Attempt != 2 || len(r.History) != 2 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	expected := []string{
+		"Vault status: Vault status is critical (soft state, attempt 1/2)",
+		"Vault status: Vault status is critical (hard state)",
+	}
+	if strings.Join(logged, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q to be %q", logged, expected)
+	}
+}
+
+func TestAgentScheduler_Handler(t *testing.T) {
+	checks := []batchCheck{
+		{Name: "Vault status", Command: "status"},
+		{Name: "Vault HA", Command: "hastatus"},
+	}
+	s := newAgentScheduler(checks, agentOptions{interval: time.Minute}, func(check batchCheck) (int, string) {
+		return StateWarning, check.Name + " output"
+	}, nil)
+	s.runCheck(checks[0])
+
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	code, result := getAgentResult(t, server.URL, "Vault status")
+	if code != http.StatusOK {
+		t.Fatalf("expected %d to be %d", code, http.StatusOK)
+	}
+	if result.State != StateWarning || result.Output != "Vault status output" || result.LastCheck == nil {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if code, _ := getAgentResult(t, server.URL, "nosuchcheck"); code != http.StatusNotFound {
+		t.Errorf("expected %d to be %d", code, http.StatusNotFound)
+	}
+
+	resp, err := http.Get(server.URL + "/checks/Vault%20status?format=nagios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Vault status output\n" || resp.Header.Get(agentStateHeader) != "1" {
+		t.Errorf("unexpected nagios result: %q (%s: %s)", body, agentStateHeader, resp.Header.Get(agentStateHeader))
+	}
+
+	resp, err = http.Get(server.URL + "/checks/Vault%20status?format=xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %d to be %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, err = http.Get(server.URL + "/checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var results []agentResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "Vault status" || results[1].Name != "Vault HA" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[1].State != StateUndefined || results[1].LastCheck != nil {
+		t.Errorf("expected the check 'Vault HA' not to be run yet: %+v", results[1])
+	}
+
+	resp, err = http.Post(server.URL+"/checks", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d to be %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAgentCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		checks string
+		args   []string
+		out    string
+		code   int
+	}{
+		{
+			"not_enough_args",
+			"",
+			[]string{},
+			"Not enough arguments (expected 1, got 0)",
+			StateUndefined,
+		},
+		{
+			"invalid_interval",
+			`"Vault status" status`,
+			[]string{"-interval", "0s"},
+			"invalid interval: 0s",
+			StateUndefined,
+		},
+		{
+			"invalid_jitter",
+			`"Vault status" status`,
+			[]string{"-jitter", "soon"},
+			"invalid jitter: soon",
+			StateUndefined,
+		},
+		{
+			"invalid_check_interval",
+			`"Vault status" @never status`,
+			[]string{},
+			":1: invalid interval: @never",
+			StateUndefined,
+		},
+		{
+			"no_checks",
+			"# nothing to do\n",
+			[]string{},
+			"No checks found in",
+			StateUndefined,
+		},
+		{
+			"invalid_listen_address",
+			`"Vault status" status`,
+			[]string{"-listen", "127.0.0.1:nosuchport"},
+			"error listening on 127.0.0.1:nosuchport",
+			StateUndefined,
+		},
+		{
+			"invalid_max_attempts",
+			`"Vault status" status`,
+			[]string{"-max-attempts", "0"},
+			"invalid number of attempts: 0",
+			StateUndefined,
+		},
+		{
+			"invalid_flap_thresholds",
+			`"Vault status" status`,
+			[]string{"-flap-low-threshold", "40", "-flap-high-threshold", "30"},
+			"invalid flap thresholds (low: 40, high: 30)",
+			StateUndefined,
+		},
+		{
+			"invalid_state_file",
+			`"Vault status" status`,
+			[]string{"-state-file", "/"},
+			"error reading the state file",
+			StateUndefined,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ui, cmd := testAgentCommand(t)
+
+			args := tc.args
+			if tc.checks != "" {
+				args = append(args, testBatchChecks(t, tc.checks))
+			}
+
+			code := cmd.Run(args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("serve", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		_, cmd := testAgentCommand(t)
+		cmd.client = client
+		cmd.OutputFormat = "nagios"
+
+		checks, err := loadChecks(testBatchChecks(t, "\"Vault status\" @1h status\nBogus nosuchcommand\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		url := "http://" + listener.Addr().String()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			errc <- newAgentScheduler(checks, agentOptions{interval: time.Hour}, cmd.runCheck, nil).serve(ctx, listener)
+		}()
+
+		results := make(map[string]agentResult)
+		for _, check := range checks {
+			for i := 0; ; i++ {
+				if _, results[check.Name] = getAgentResult(t, url, check.Name); results[check.Name].LastCheck != nil {
+					break
+				}
+				if i == 100 {
+					t.Fatalf("the check %s has not been run", check.Name)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+
+		if r := results["Vault status"]; r.State != StateOk ||
+			!strings.HasPrefix(r.Output, "vault OK - Vault (") || r.Interval != "1h0m0s" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+		if r := results["Bogus"]; r.State != StateUndefined || r.Output != "unknown command: nosuchcommand" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+}
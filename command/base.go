@@ -17,7 +17,12 @@
 package command
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
@@ -25,14 +30,28 @@ import (
 
 // BaseCommand is a Command that holds the common command options
 type BaseCommand struct {
+	CommandName       string
+	ServiceName       string
 	Address           string
 	OutputFormat      string
+	MessageTemplate   string
+	EventURL          string
+	EventTemplate     string
+	EventRetries      int
+	SpoolDir          string
+	Textfile          string
+	OTLPEndpoint      string
+	OTLPProtocol      string
 	Token             string
 	TokenAccessor     string
 	UI                cli.Ui
 	client            *api.Client
+	sharedClient      *api.Client
+	telemetry         *telemetry
+	States            stateFlag
 	UnknownAsCritical bool
 	SealedAsWarning   bool
+	outcomes          []checkOutcome
 }
 
 // Client returs a new HTTP API Vault client for the given configuration
@@ -40,37 +59,224 @@ type BaseCommand struct {
 //
 // If the environment variable `VAULT_TOKEN` is present, the token will be
 // automatically added to the client.
+//
+// The checks run by the `batch` and `agent` commands get a copy of the client
+// shared by all of them, so that their connections are reused.
 func (c *BaseCommand) Client() (*api.Client, error) {
 	// Read the test client if present
 	if c.client != nil {
 		return c.client, nil
 	}
 
-	// Create a configuration for Vault looking at env and command-line args
-	config := api.DefaultConfig()
-	if config == nil {
-		return nil, fmt.Errorf("could not create/read default configuration for Vault")
+	var client *api.Client
+	var err error
+	if c.sharedClient != nil {
+		if client, err = c.sharedClient.Clone(); err != nil {
+			return nil, err
+		}
+		client.SetToken(c.sharedClient.Token())
+
+		if c.Address != "" && c.Address != addressDefault {
+			if err := client.SetAddress(c.Address); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Create a configuration for Vault looking at env and command-line args
+		config := api.DefaultConfig()
+		if config == nil {
+			return nil, fmt.Errorf("could not create/read default configuration for Vault")
+		}
+
+		if err := config.ReadEnvironment(); err != nil {
+			return nil, fmt.Errorf("failed to read environment: %s", err)
+		}
+
+		if c.Address != "" && c.Address != addressDefault {
+			config.Address = c.Address
+		}
+
+		// Build the client
+		if client, err = api.NewClient(config); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := config.ReadEnvironment(); err != nil {
-		return nil, fmt.Errorf("failed to read environment: %s", err)
+	if c.Token != "" {
+		client.SetToken(c.Token)
 	}
 
-	if c.Address != "" && c.Address != addressDefault {
-		config.Address = c.Address
+	if c.telemetry != nil {
+		if client, err = c.telemetry.traceClient(client); err != nil {
+			return nil, err
+		}
 	}
 
-	// Build the client
-	client, err := api.NewClient(config)
+	c.client = client
+
+	return client, nil
+}
+
+// Service returns the name of the service reporting the command results in
+// the monitoring tools: the given service name or, by default, the name of
+// the command prefixed by "Vault".
+func (c *BaseCommand) Service() string {
+	if c.ServiceName != "" {
+		return c.ServiceName
+	}
+	return strings.TrimSpace("Vault " + c.CommandName)
+}
+
+// NodeClient returns a copy of the Vault client, using the same token and
+// TLS configuration, that sends its requests to the node at the given address.
+func (c *BaseCommand) NodeClient(address string) (*api.Client, error) {
+	client, err := c.Client()
 	if err != nil {
 		return nil, err
 	}
 
-	if c.Token != "" {
-		client.SetToken(c.Token)
+	node, err := client.Clone()
+	if err != nil {
+		return nil, err
 	}
+	if err := node.SetAddress(address); err != nil {
+		return nil, err
+	}
+	node.SetToken(client.Token())
 
-	c.client = client
+	return node, nil
+}
 
-	return client, nil
+// clusterNodes returns the addresses of the cluster nodes, as given in the
+// comma separated list nodes and, if discover is set, as listed by the
+// sys/ha-status endpoint.
+func (c *BaseCommand) clusterNodes(nodes string, discover bool) ([]string, error) {
+	var addresses []string
+
+	if discover {
+		client, err := c.Client()
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := client.Sys().HAStatus()
+		if err != nil {
+			return nil, fmt.Errorf("error discovering the cluster nodes: %s", err)
+		}
+		for _, n := range status.Nodes {
+			addresses = append(addresses, n.APIAddress)
+		}
+	}
+
+	for _, address := range strings.Split(nodes, ",") {
+		if address = strings.TrimSpace(address); address != "" && !contains(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, errors.New("No cluster nodes to check")
+	}
+
+	return addresses, nil
+}
+
+// queryNodes concurrently queries the seal and leader status of the nodes
+// at the given addresses.
+func (c *BaseCommand) queryNodes(addresses []string) []haNodeStatus {
+	nodes := make([]haNodeStatus, len(addresses))
+
+	// create the shared client before the goroutines cloning it
+	if _, err := c.Client(); err != nil {
+		for i, address := range addresses {
+			nodes[i] = haNodeStatus{Address: address, Err: err}
+		}
+		return nodes
+	}
+
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			nodes[i] = c.nodeStatus(address)
+		}(i, address)
+	}
+	wg.Wait()
+
+	return nodes
+}
+
+// nodeStatus queries the seal and leader status of the node at address.
+func (c *BaseCommand) nodeStatus(address string) haNodeStatus {
+	node := haNodeStatus{Address: address}
+
+	client, err := c.NodeClient(address)
+	if err != nil {
+		node.Err = err
+		return node
+	}
+
+	sealStatus, err := client.Sys().SealStatus()
+	if err != nil {
+		node.Err = err
+		return node
+	}
+	node.Sealed = sealStatus.Sealed
+	node.Version = sealStatus.Version
+	node.ClusterName = sealStatus.ClusterName
+
+	if node.Sealed {
+		return node
+	}
+
+	leader, err := client.Sys().Leader()
+	if err != nil {
+		node.Err = err
+		return node
+	}
+	node.HAEnabled = leader.HAEnabled
+	node.Active = leader.IsSelf
+	node.PerfStandby = leader.PerfStandby
+	node.LeaderAddress = leader.LeaderAddress
+
+	return node
+}
+
+// decodeData converts the generic data returned by the Vault API (usually
+// the Data field of an api.Secret) into the given structure, according to
+// its `json` field tags.
+func decodeData(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// stringSliceFlag is a flag.Value collecting the values of a flag that can
+// be repeated on the command line.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// parseDurationThresholds parses the warning and critical thresholds and
+// return their corresponding Duration values.
+func parseDurationThresholds(warning, critical string) (time.Duration, time.Duration, error) {
+	warningThreshold, err := time.ParseDuration(warning)
+	if err != nil {
+		return 0, 0, err
+	}
+	criticalThreshold, err := time.ParseDuration(critical)
+	if err != nil {
+		return 0, 0, err
+	}
+	return warningThreshold, criticalThreshold, nil
 }
@@ -0,0 +1,331 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+// DefaultBatchConcurrency is the default number of checks run concurrently
+// by the `batch` command.
+const DefaultBatchConcurrency = 4
+
+const batchConcurrencyDescr = "Number of checks run concurrently (default: %d)"
+
+// BatchCommand is a CLI Command that holds the attributes of the command `batch`.
+type BatchCommand struct {
+	*BaseCommand
+	Concurrency int
+}
+
+// batchCheck is a check run by the `batch` and `agent` commands.
+// The interval is only used by the `agent` command.
+type batchCheck struct {
+	Name     string
+	Interval time.Duration
+	Command  string
+	Args     []string
+}
+
+// splitFields splits a line into fields separated by spaces.
+// A field can be enclosed in single or double quotes to include spaces.
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
+
+// loadChecks reads the checks to be run from the file at path.
+// Every line holds the name of the service, optionally followed by the
+// interval of the check in the form @DURATION, and then by the command and
+// its arguments. Empty lines and lines starting with a '#' are ignored.
+func loadChecks(path string) ([]batchCheck, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var checks []batchCheck
+	names := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields, err := splitFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, lineno, err)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected a service name and a command", path, lineno)
+		}
+		if names[fields[0]] {
+			return nil, fmt.Errorf("%s:%d: duplicate service name: %s", path, lineno, fields[0])
+		}
+		names[fields[0]] = true
+
+		check := batchCheck{Name: fields[0]}
+		if strings.HasPrefix(fields[1], "@") {
+			interval, err := time.ParseDuration(fields[1][1:])
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("%s:%d: invalid interval: %s", path, lineno, fields[1])
+			}
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("%s:%d: expected a command after the interval", path, lineno)
+			}
+			check.Interval = interval
+			fields = fields[1:]
+		}
+		check.Command = fields[1]
+		check.Args = fields[2:]
+
+		checks = append(checks, check)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
+
+// runCheck runs the given check and returns its exit code and output.
+// The check uses a copy of the Vault client of c, if it has been created.
+func (c *BaseCommand) runCheck(check batchCheck) (int, string) {
+	var buf bytes.Buffer
+	ui := &cli.BasicUi{Writer: &buf, ErrorWriter: &buf}
+
+	factories := commandFactories(func(name string) *BaseCommand {
+		base := newBaseCommand(ui, name)
+		base.ServiceName = check.Name
+		base.sharedClient = c.client
+		return base
+	})
+
+	factory, ok := factories[check.Command]
+	if !ok || check.Command == "batch" || check.Command == "agent" {
+		return StateUndefined, fmt.Sprintf("unknown command: %s", check.Command)
+	}
+	cmd, err := factory()
+	if err != nil {
+		return StateUndefined, err.Error()
+	}
+
+	args := c.outputArgs()
+	if c.Address != addressDefault {
+		args = append(args, "-address", c.Address)
+	}
+	if c.Token != tokenDefault {
+		args = append(args, "-token", c.Token)
+	}
+
+	code := cmd.Run(append(args, check.Args...))
+	return code, strings.TrimRight(buf.String(), "\n")
+}
+
+// Synopsis returns a short synopsis of the `batch` command.
+func (c *BatchCommand) Synopsis() string {
+	return "Run the checks listed in a file and report one result per check"
+}
+
+// Help returns a long-form help text of the `batch` command.
+func (c *BatchCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor batch [options] FILE
+
+  This command runs all the checks listed in FILE and reports the result of
+  every check. Each line of the file holds the name of the service, followed
+  by the command and its arguments. The service names containing spaces must
+  be quoted. Empty lines and lines starting with a '#' are ignored. The
+  intervals of the checks (@DURATION, see the 'agent' command) are ignored.
+
+    # service        command    arguments
+    "Vault status"   status
+    "Vault HA"       hastatus   -discover
+    "Vault token"    token-lookup -warning 240h
+
+  With the 'checkmk' output format every check is reported as a Checkmk
+  local check, so this command can be run by a script placed in the
+  local checks directory of the Checkmk agent:
+
+    $ hashicorp-vault-monitor batch -output checkmk /etc/vault-monitor/checks
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+    -concurrency=<int>
+       Number of checks run concurrently (default: %d).
+
+  The exit code reflects the worst state of the checks:
+
+      - %d - all the checks are ok
+      - %d - at least one check returned a warning
+      - %d - at least one check returned a critical state
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		batchOutputFlagsHelp(),
+		DefaultBatchConcurrency,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `batch` command with the given CLI instance and command-line arguments.
+func (c *BatchCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.IntVar(&c.Concurrency, "concurrency",
+		DefaultBatchConcurrency,
+		fmt.Sprintf(batchConcurrencyDescr, DefaultBatchConcurrency))
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	// the results are exported by the checks themselves
+	out, err := c.outputter()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	switch {
+	case len(args) < 1:
+		out.Undefined("Not enough arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	case len(args) > 1:
+		out.Undefined("Too many arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if c.Concurrency < 1 {
+		out.Undefined("The concurrency must be at least 1")
+		return StateUndefined
+	}
+
+	checks, err := loadChecks(args[0])
+	if err != nil {
+		out.Undefined("error reading the checks: %s", err)
+		return StateUndefined
+	}
+	if len(checks) == 0 {
+		out.Undefined("No checks found in %s", args[0])
+		return StateUndefined
+	}
+
+	// the Vault client, and its connections, are shared by all the checks
+	if _, err := c.Client(); err != nil {
+		out.Undefined("error creating the Vault client: %s", err)
+		return StateUndefined
+	}
+
+	codes := make([]int, len(checks))
+	outputs := make([]string, len(checks))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < c.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				codes[i], outputs[i] = c.runCheck(checks[i])
+			}
+		}()
+	}
+	for i := range checks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, check := range checks {
+		output := outputs[i]
+		if c.OutputFormat == "checkmk" {
+			// the errors reported before the output handling is set up
+			// (for instance the flag parsing errors, following the usage
+			// message) are not in the Checkmk format
+			prefix := fmt.Sprintf("%d \"%s\" ", codes[i], check.Name)
+			if !strings.HasPrefix(output, prefix) || strings.Contains(output, "\n") {
+				lines := strings.Split(output, "\n")
+				output = prefix + "- " + lines[len(lines)-1]
+			}
+		} else {
+			output = check.Name + ": " + output
+		}
+		c.UI.Info(output)
+	}
+
+	return WorstState(codes...)
+}
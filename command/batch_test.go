@@ -0,0 +1,230 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testBatchCommand(t *testing.T) (*cli.MockUi, *BatchCommand) {
+	t.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &BatchCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func testBatchChecks(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "checks")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSplitFields(t *testing.T) {
+	cases := []struct {
+		line     string
+		shouldbe []string
+		fail     bool
+	}{
+		{"status", []string{"status"}, false},
+		{"  Vault\tstatus  -output nagios ", []string{"Vault", "status", "-output", "nagios"}, false},
+		{`"Vault status" status`, []string{"Vault status", "status"}, false},
+		{`'Vault "HA"' hastatus ""`, []string{`Vault "HA"`, "hastatus", ""}, false},
+		{`"Vault status status`, nil, true},
+	}
+
+	for _, tc := range cases {
+		fields, err := splitFields(tc.line)
+		if (err != nil) != tc.fail {
+			t.Errorf("For %q unexpected error value: %v", tc.line, err)
+		}
+		if diff := deep.Equal(fields, tc.shouldbe); diff != nil {
+			t.Errorf("For %q: %v", tc.line, diff)
+		}
+	}
+}
+
+func TestBaseCommand_SharedClient(t *testing.T) {
+	shared, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared.SetToken("batch-token")
+
+	cases := []struct {
+		name    string
+		address string
+		token   string
+		addr    string
+		tok     string
+	}{
+		{"inherited", "", "", shared.Address(), "batch-token"},
+		{"overridden", "https://vault.example.com:8200", "check-token",
+			"https://vault.example.com:8200", "check-token"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			base := &BaseCommand{Address: tc.address, Token: tc.token, sharedClient: shared}
+			client, err := base.Client()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if client == shared {
+				t.Fatalf("expected a copy of the shared client")
+			}
+			if client.Address() != tc.addr || client.Token() != tc.tok {
+				t.Errorf("expected the address %s and the token %s, got %s and %s",
+					tc.addr, tc.tok, client.Address(), client.Token())
+			}
+			if client.CloneConfig().HttpClient.Transport != shared.CloneConfig().HttpClient.Transport {
+				t.Errorf("expected the transport of the shared client to be reused")
+			}
+			if shared.Token() != "batch-token" {
+				t.Errorf("the shared client has been modified")
+			}
+		})
+	}
+}
+
+func TestBatchCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	checks := `# Vault checks
+"Vault status"   status
+
+"Vault policies" policies root nosuchpolicy
+Bogus            nosuchcommand
+`
+
+	cases := []struct {
+		name   string
+		checks string
+		args   []string
+		out    []string
+		code   int
+	}{
+		{
+			"not_enough_args",
+			"",
+			[]string{},
+			[]string{"Not enough arguments (expected 1, got 0)"},
+			StateUndefined,
+		},
+		{
+			"default_output",
+			checks,
+			[]string{},
+			[]string{
+				"Vault status: Vault (TestBatchCommand_Run/default_output) is unsealed\n",
+				"Vault policies: no such Vault policy: nosuchpolicy\n" +
+					"[OK] policy root is defined\n" +
+					"[CRITICAL] no such Vault policy: nosuchpolicy\n",
+				"Bogus: unknown command: nosuchcommand\n",
+			},
+			StateCritical,
+		},
+		{
+			"checkmk_output",
+			checks,
+			[]string{"-output", "checkmk", "-concurrency", "1"},
+			[]string{
+				"0 \"Vault status\" sealed=0;;;0;1|unseal_progress=0;;;0;3 Vault (TestBatchCommand_Run/checkmk_output) is unsealed\n" +
+					"2 \"Vault policies\" - no such Vault policy: nosuchpolicy" +
+					"\\n[OK] policy root is defined\\n[CRITICAL] no such Vault policy: nosuchpolicy\n" +
+					"3 \"Bogus\" - unknown command: nosuchcommand\n",
+			},
+			StateCritical,
+		},
+		{
+			"token_flag",
+			"\"Vault status\" status\n\"Vault HA\" hastatus\n",
+			[]string{"-token", "s.testtoken", "-output", "checkmk"},
+			[]string{
+				"0 \"Vault status\" sealed=0;;;0;1|unseal_progress=0;;;0;3 Vault (TestBatchCommand_Run/token_flag) is unsealed\n",
+				"0 \"Vault HA\" ",
+			},
+			StateOk,
+		},
+		{
+			"checkmk_flag_error",
+			`"Vault status" status -nosuchflag`,
+			[]string{"-output", "checkmk"},
+			[]string{"3 \"Vault status\" - flag provided but not defined: -nosuchflag\n"},
+			StateUndefined,
+		},
+		{
+			"duplicate_service",
+			"Vault status\nVault policies root\n",
+			[]string{},
+			[]string{":2: duplicate service name: Vault"},
+			StateUndefined,
+		},
+		{
+			"no_checks",
+			"# nothing to do\n",
+			[]string{},
+			[]string{"No checks found in"},
+			StateUndefined,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, _, closer := testVaultServerUnseal(t)
+			defer closer()
+
+			ui, cmd := testBatchCommand(t)
+			cmd.client = client
+
+			args := tc.args
+			if tc.checks != "" {
+				args = append(args, testBatchChecks(t, tc.checks))
+			}
+
+			code := cmd.Run(args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, expected := range tc.out {
+				if !strings.Contains(combined, expected) {
+					t.Errorf("expected %q to contain %q", combined, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Default values for the `canary` command.
+const (
+	DefaultCanaryReadTimeout = "2s"
+)
+
+const (
+	canaryReadTimeoutDescr = "Time to wait for the canary to be readable from the other nodes (default: %s)"
+	canaryKeepDescr        = "Do not delete the canary secret at the end of the check"
+)
+
+// canaryRetryInterval is the time between two reads of the canary secret
+// from the cluster nodes.
+const canaryRetryInterval = 100 * time.Millisecond
+
+// canaryOutcomes are the outcomes of the `canary` command.
+var canaryOutcomes = []checkOutcome{
+	{"completed", StateOk, "the canary has been written, read back and deleted"},
+	{"write-failed", StateCritical, "the canary cannot be written"},
+	{"read-failed", StateCritical, "the canary cannot be read back, or its value does not match"},
+	{"delete-failed", StateWarning, "the canary cannot be deleted"},
+}
+
+// CanaryCommand is a CLI Command that holds the attributes of the command `canary`.
+type CanaryCommand struct {
+	*BaseCommand
+	Path        string
+	ReadTimeout string
+	Keep        bool
+	Nodes       string
+	Discover    bool
+}
+
+// kvSecretPath is the path of a secret along with the KV secrets engine
+// it belongs to.
+type kvSecretPath struct {
+	mount   string
+	version int
+	path    string
+}
+
+// kvMountInfo returns the KV secrets engine mounted at the given path.
+func kvMountInfo(client *api.Client, path string) (*kvSecretPath, error) {
+	secret, err := client.Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no secrets engine found at %s", path)
+	}
+
+	var mount struct {
+		Path    string            `json:"path"`
+		Type    string            `json:"type"`
+		Options map[string]string `json:"options"`
+	}
+	if err := decodeData(secret.Data, &mount); err != nil {
+		return nil, err
+	}
+	if mount.Type != "kv" && mount.Type != "generic" {
+		return nil, fmt.Errorf("%s is not a KV secrets engine (type: %s)", mount.Path, mount.Type)
+	}
+
+	kv := &kvSecretPath{
+		mount:   mount.Path,
+		version: 1,
+		path:    strings.Trim(strings.TrimPrefix(path+"/", mount.Path), "/"),
+	}
+	if mount.Options["version"] == "2" {
+		kv.version = 2
+	}
+	if kv.path == "" {
+		return nil, fmt.Errorf("%s is the path of a secrets engine, not of a secret", path)
+	}
+	return kv, nil
+}
+
+// dataPath returns the API path for reading and writing the secret.
+func (kv *kvSecretPath) dataPath() string {
+	if kv.version == 2 {
+		return kv.mount + "data/" + kv.path
+	}
+	return kv.mount + kv.path
+}
+
+// deletePath returns the API path for permanently deleting the secret.
+func (kv *kvSecretPath) deletePath() string {
+	if kv.version == 2 {
+		return kv.mount + "metadata/" + kv.path
+	}
+	return kv.mount + kv.path
+}
+
+// write stores the given data in the secret.
+func (kv *kvSecretPath) write(client *api.Client, data map[string]interface{}) error {
+	if kv.version == 2 {
+		data = map[string]interface{}{"data": data}
+	}
+	_, err := client.Logical().Write(kv.dataPath(), data)
+	return err
+}
+
+// read returns the value of the given field of the secret.
+func (kv *kvSecretPath) read(client *api.Client, field string) (string, error) {
+	secret, err := client.Logical().Read(kv.dataPath())
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no data found at %s", kv.dataPath())
+	}
+
+	data := secret.Data
+	if kv.version == 2 {
+		data, _ = secret.Data["data"].(map[string]interface{})
+	}
+	value, _ := data[field].(string)
+	return value, nil
+}
+
+// randomNonce returns a random hexadecimal string.
+func randomNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// readFromNode reads the canary secret from the node at address until its
+// value matches the expected one or the timeout expires.
+func (c *CanaryCommand) readFromNode(kv *kvSecretPath, address, expected string,
+	timeout time.Duration) error {
+	client, err := c.NodeClient(address)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		value, err := kv.read(client, "value")
+		switch {
+		case err == nil && value == expected:
+			return nil
+		case time.Now().After(deadline):
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("read value '%s' instead of '%s'", value, expected)
+		}
+		time.Sleep(canaryRetryInterval)
+	}
+}
+
+// Synopsis returns a short synopsis of the `canary` command.
+func (c *CanaryCommand) Synopsis() string {
+	return "Write, read back and delete a canary secret in the KV store"
+}
+
+// Help returns a long-form help text of the `canary` command.
+func (c *CanaryCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor canary [options] KEY
+
+  This command writes a random value in the KV store at the given key name,
+  reads it back, checks that the value read is the one written and finally
+  deletes the secret. Both the KV version 1 and version 2 secrets engines
+  are supported.
+
+    $ hashicorp-vault-monitor canary secret/monitoring/canary
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -nodes=<string>
+       Comma separated list of the addresses of the cluster nodes (standby
+       and performance standby nodes) the canary must also be read from.
+
+    -discover
+       Also read the canary from all the cluster nodes, as listed by the
+       sys/ha-status endpoint of the node at -address.
+
+    -read-timeout=<duration>
+       Time to wait for the canary to be readable from the cluster nodes
+       (default: %s). This takes into account the replication delay of the
+       performance standby nodes.
+
+    -keep
+       Do not delete the canary secret at the end of the check.
+
+  The token must be allowed to create, read and delete the secret (and, for
+  the KV version 2 secrets engine, to delete its metadata) and to read the
+  'sys/internal/ui/mounts' path.
+
+  By default, the exit code reflects the result of the check:
+
+      - %d - the canary has been written, read back and deleted
+      - %d - the canary could not be deleted
+      - %d - the canary could not be written or read back, or its value does
+            not match the written one
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("delete-failed=critical", canaryOutcomes),
+		DefaultCanaryReadTimeout,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `canary` command with the given CLI instance and command-line arguments.
+func (c *CanaryCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("canary", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, canaryOutcomes)
+	cmdFlags.StringVar(&c.Nodes, "nodes", "", haStatusNodesDescr)
+	cmdFlags.BoolVar(&c.Discover, "discover", false, haStatusDiscoverDescr)
+	cmdFlags.StringVar(&c.ReadTimeout, "read-timeout",
+		DefaultCanaryReadTimeout,
+		fmt.Sprintf(canaryReadTimeoutDescr, DefaultCanaryReadTimeout))
+	cmdFlags.BoolVar(&c.Keep, "keep", false, canaryKeepDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	switch {
+	case len(args) < 1:
+		out.Undefined("Not enough arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	case len(args) > 1:
+		out.Undefined("Too many arguments (expected 1, got %d)", len(args))
+		return StateUndefined
+	}
+
+	c.Path = strings.Trim(args[0], "/")
+
+	readTimeout, err := time.ParseDuration(c.ReadTimeout)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	kv, err := kvMountInfo(client, c.Path)
+	if err != nil {
+		return c.report(out, "error", "error looking up the KV secrets engine of %s: %s", c.Path, err)
+	}
+
+	var addresses []string
+	if c.Nodes != "" || c.Discover {
+		if addresses, err = c.clusterNodes(c.Nodes, c.Discover); err != nil {
+			return c.report(out, "error", "%s", err)
+		}
+	}
+
+	value, err := randomNonce()
+	if err != nil {
+		return c.report(out, "error", "error generating the canary: %s", err)
+	}
+
+	if err := kv.write(client, map[string]interface{}{
+		"value":     value,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return c.report(out, "write-failed", "error writing the canary %s: %s", c.Path, err)
+	}
+
+	var problems []string
+	retCode := c.state("completed")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, c.state(outcome))
+	}
+
+	if read, err := kv.read(client, "value"); err != nil {
+		report("read-failed", "error reading the canary: %s", err)
+	} else if read != value {
+		report("read-failed", "read value '%s' instead of '%s'", read, value)
+	}
+
+	nodeErrors := make([]error, len(addresses))
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			nodeErrors[i] = c.readFromNode(kv, address, value, readTimeout)
+		}(i, address)
+	}
+	wg.Wait()
+
+	for i, err := range nodeErrors {
+		if err != nil {
+			report("read-failed", "%s: %s", addresses[i], err)
+		}
+	}
+
+	if !c.Keep {
+		if _, err := client.Logical().Delete(kv.deletePath()); err != nil {
+			report("delete-failed", "error deleting the canary: %s", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		out.State(retCode)("canary %s: %s", c.Path, strings.Join(problems, "; "))
+		return retCode
+	}
+
+	checked := ""
+	if len(addresses) > 0 {
+		checked = fmt.Sprintf(" (read from %d nodes)", len(addresses))
+	}
+	action := "deleted"
+	if c.Keep {
+		action = "kept"
+	}
+	return c.report(out, "completed", "canary %s (KV v%d) written, read back and %s%s",
+		c.Path, kv.version, action, checked)
+}
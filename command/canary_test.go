@@ -0,0 +1,195 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testCanaryCommand(t *testing.T) (*cli.MockUi, *CanaryCommand) {
+	ui := cli.NewMockUi()
+	return ui, &CanaryCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestCanaryCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor canary [options] KEY",
+			StateUndefined,
+		},
+		{
+			"not_enough_args",
+			[]string{},
+			"Not enough arguments",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1", "arg2"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"kv_v1",
+			[]string{"secret/monitoring/canary"},
+			"canary secret/monitoring/canary (KV v1) written, read back and deleted",
+			StateOk,
+		},
+		{
+			"kv_v2",
+			[]string{"kv2/monitoring/canary"},
+			"canary kv2/monitoring/canary (KV v2) written, read back and deleted",
+			StateOk,
+		},
+		{
+			"keep",
+			[]string{"-keep", "secret/canary"},
+			"canary secret/canary (KV v1) written, read back and kept",
+			StateOk,
+		},
+		{
+			"nodes",
+			[]string{"-nodes", "NODE_ADDRESS", "secret/canary"},
+			"written, read back and deleted (read from 1 nodes)",
+			StateOk,
+		},
+		{
+			"unreachable_node",
+			[]string{"-nodes", "NODE_ADDRESS,https://127.0.0.1:1", "-read-timeout", "0s", "secret/canary"},
+			"canary secret/canary: https://127.0.0.1:1: ",
+			StateCritical,
+		},
+		{
+			"mount_path",
+			[]string{"secret/"},
+			"secret is the path of a secrets engine, not of a secret",
+			StateUndefined,
+		},
+		{
+			"not_kv",
+			[]string{"transit/canary"},
+			"transit/ is not a KV secrets engine (type: transit)",
+			StateUndefined,
+		},
+		{
+			"no_mount",
+			[]string{"nosuchmount/canary"},
+			"error looking up the KV secrets engine of nosuchmount/canary",
+			StateUndefined,
+		},
+		{
+			"nagios_canary",
+			[]string{"-output", "nagios", "secret/canary"},
+			"vault OK - canary secret/canary (KV v1) written",
+			StateOk,
+		},
+	}
+
+	t.Run("canary", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, _, closer := testVaultServerUnseal(t)
+				defer closer()
+
+				if err := client.Sys().Mount("kv2", &api.MountInput{
+					Type:    "kv",
+					Options: map[string]string{"version": "2"},
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.Sys().Mount("transit", &api.MountInput{
+					Type: "transit",
+				}); err != nil {
+					t.Fatal(err)
+				}
+
+				var args []string
+				for _, arg := range tc.args {
+					args = append(args, strings.Replace(arg, "NODE_ADDRESS", client.Address(), 1))
+				}
+
+				ui, cmd := testCanaryCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+
+				if code != StateOk {
+					return
+				}
+				kv, err := kvMountInfo(client, args[len(args)-1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				secret, err := client.Logical().Read(kv.dataPath())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if kept := secret != nil; kept != cmd.Keep {
+					t.Errorf("expected the canary to be kept: %t, got %t", cmd.Keep, kept)
+				}
+			})
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testCanaryCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"secret/canary"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error looking up the KV secrets engine of secret/canary: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
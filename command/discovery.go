@@ -0,0 +1,254 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// discoveryTypes lists the kinds of items that can be discovered.
+var discoveryTypes = []string{"mounts", "nodes", "policies", "tokens"}
+
+// DiscoveryCommand is a CLI Command that holds the attributes of the command `discovery`.
+type DiscoveryCommand struct {
+	*BaseCommand
+	Concurrency int
+}
+
+// discoveredItem holds the Zabbix low-level discovery macros of an item.
+type discoveredItem map[string]string
+
+// discoverMounts returns the secrets engines and the authentication methods.
+func discoverMounts(client *api.Client) ([]discoveredItem, error) {
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return nil, err
+	}
+	auths, err := client.Sys().ListAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []discoveredItem
+	for path, m := range mounts {
+		items = append(items, discoveredItem{
+			"{#MOUNT}":       path,
+			"{#TYPE}":        m.Type,
+			"{#KIND}":        "secret",
+			"{#DESCRIPTION}": m.Description,
+		})
+	}
+	for path, m := range auths {
+		items = append(items, discoveredItem{
+			"{#MOUNT}":       "auth/" + path,
+			"{#TYPE}":        m.Type,
+			"{#KIND}":        "auth",
+			"{#DESCRIPTION}": m.Description,
+		})
+	}
+	return items, nil
+}
+
+// discoverNodes returns the nodes of the HA cluster.
+func discoverNodes(client *api.Client) ([]discoveredItem, error) {
+	status, err := client.Sys().HAStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []discoveredItem
+	for _, n := range status.Nodes {
+		items = append(items, discoveredItem{
+			"{#NODE}":     n.APIAddress,
+			"{#HOSTNAME}": n.Hostname,
+			"{#ACTIVE}":   strconv.FormatBool(n.ActiveNode),
+		})
+	}
+	return items, nil
+}
+
+// discoverPolicies returns the policies.
+func discoverPolicies(client *api.Client) ([]discoveredItem, error) {
+	policies, err := client.Sys().ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []discoveredItem
+	for _, policy := range policies {
+		items = append(items, discoveredItem{"{#POLICY}": policy})
+	}
+	return items, nil
+}
+
+// discoverTokens returns the tokens, as listed by their accessors.
+func discoverTokens(client *api.Client, concurrency int) ([]discoveredItem, error) {
+	accessors, err := listAccessors(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []discoveredItem
+	for _, token := range lookupAccessors(client, accessors, concurrency) {
+		if token.err != nil {
+			// the token has expired in the meantime
+			continue
+		}
+		items = append(items, discoveredItem{
+			"{#ACCESSOR}":     token.Accessor,
+			"{#DISPLAY_NAME}": token.DisplayName,
+			"{#POLICIES}":     strings.Join(token.Policies, ","),
+			"{#CREATOR}":      token.creator,
+			"{#EXPIRES}":      strconv.FormatBool(!token.expires.IsZero()),
+		})
+	}
+	return items, nil
+}
+
+// Synopsis returns a short synopsis of the `discovery` command.
+func (c *DiscoveryCommand) Synopsis() string {
+	return "List the Vault items in the Zabbix low-level discovery format"
+}
+
+// Help returns a long-form help text of the `discovery` command.
+func (c *DiscoveryCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor discovery [options] TYPE
+
+  This command lists the Vault items of the given TYPE as a JSON array, in
+  the Zabbix low-level discovery (LLD) format, so that Zabbix can create the
+  items checking each of them.
+
+    $ hashicorp-vault-monitor discovery policies
+
+  The supported types and the related LLD macros are:
+
+      mounts     {#MOUNT}, {#TYPE}, {#KIND} ('secret' or 'auth'), {#DESCRIPTION}
+      nodes      {#NODE} (API address), {#HOSTNAME}, {#ACTIVE}
+      policies   {#POLICY}
+      tokens     {#ACCESSOR}, {#DISPLAY_NAME}, {#POLICIES}, {#CREATOR}, {#EXPIRES}
+
+  The discovery of the tokens requires the sudo capability on the path
+  auth/token/accessors.
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+    -concurrency=<int>
+       Number of concurrent lookups of the token accessors (default: %d).
+
+  The exit code is %d, or %d if an error occurred.
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		DefaultTokenLookupConcurrency,
+		StateOk, StateUndefined)
+}
+
+// Run executes the `discovery` command with the given CLI instance and command-line arguments.
+func (c *DiscoveryCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("discovery", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	cmdFlags.IntVar(&c.Concurrency, "concurrency",
+		DefaultTokenLookupConcurrency,
+		fmt.Sprintf(tokenConcurrencyDescr, DefaultTokenLookupConcurrency))
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return StateUndefined
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return StateUndefined
+	}
+
+	if c.Concurrency < 1 {
+		c.UI.Error("The concurrency must be at least 1")
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	var items []discoveredItem
+	var key string
+
+	switch args[0] {
+	case "mounts":
+		items, err = discoverMounts(client)
+		key = "{#MOUNT}"
+	case "nodes":
+		items, err = discoverNodes(client)
+		key = "{#NODE}"
+	case "policies":
+		items, err = discoverPolicies(client)
+		key = "{#POLICY}"
+	case "tokens":
+		items, err = discoverTokens(client, c.Concurrency)
+		key = "{#ACCESSOR}"
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown discovery type: %s (expected one of: %s)",
+			args[0], strings.Join(discoveryTypes, ", ")))
+		return StateUndefined
+	}
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("error discovering the %s: %s", args[0], err))
+		return StateUndefined
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i][key] < items[j][key]
+	})
+	if items == nil {
+		items = []discoveredItem{}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+	c.UI.Output(string(data))
+
+	return StateOk
+}
@@ -0,0 +1,156 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testDiscoveryCommand(t *testing.T) (*cli.MockUi, *DiscoveryCommand) {
+	t.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &DiscoveryCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestDiscoveryCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			[]string{},
+			"Not enough arguments (expected 1, got 0)",
+			StateUndefined,
+		},
+		{
+			"unknown_type",
+			[]string{"leases"},
+			"Unknown discovery type: leases (expected one of: mounts, nodes, policies, tokens)",
+			StateUndefined,
+		},
+		{
+			"policies",
+			[]string{"policies"},
+			`[{"{#POLICY}":"default"},{"{#POLICY}":"root"}]`,
+			StateOk,
+		},
+		{
+			"mounts",
+			[]string{"mounts"},
+			`{"{#DESCRIPTION}":"token based credentials","{#KIND}":"auth","{#MOUNT}":"auth/token/","{#TYPE}":"token"}`,
+			StateOk,
+		},
+		{
+			"tokens",
+			[]string{"tokens"},
+			`"{#CREATOR}":"auth/token/create","{#DISPLAY_NAME}":"token-discovery","{#EXPIRES}":"true","{#POLICIES}":"default"}`,
+			StateOk,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, _, closer := testVaultServerUnseal(t)
+			defer closer()
+
+			if _, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+				DisplayName: "discovery",
+				Policies:    []string{"default"},
+				TTL:         "1h",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			ui, cmd := testDiscoveryCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("nodes", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{
+			"/v1/sys/ha-status": `{"nodes": [
+				{"hostname": "vault-2", "api_address": "https://10.0.0.2:8200", "active_node": false},
+				{"hostname": "vault-1", "api_address": "https://10.0.0.1:8200", "active_node": true}]}`,
+		})
+		defer closer()
+
+		ui, cmd := testDiscoveryCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"nodes"})
+		if exp := StateOk; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := `[{"{#ACTIVE}":"true","{#HOSTNAME}":"vault-1","{#NODE}":"https://10.0.0.1:8200"},` +
+			`{"{#ACTIVE}":"false","{#HOSTNAME}":"vault-2","{#NODE}":"https://10.0.0.2:8200"}]`
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testDiscoveryCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"policies"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error making API request."
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -0,0 +1,381 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Default values for the `sensu` and `webhook` output formats.
+const (
+	DefaultSensuURL     = "http://127.0.0.1:3031/events"
+	DefaultEventRetries = 3
+)
+
+const (
+	eventURLDescr      = "URL the events are posted to (default for the sensu output: %s)"
+	eventTemplateDescr = "File holding the text/template of the body of the webhook events"
+	eventRetriesDescr  = "Number of retries when posting an event fails (default: %d)"
+	eventSpoolDirDescr = "Directory where the events that cannot be posted are spooled"
+)
+
+// eventTimeout is the timeout of the HTTP requests posting the events.
+const eventTimeout = 10 * time.Second
+
+// spoolRejectedSuffix is appended to the names of the spooled events that
+// cannot be decoded or that are rejected by the endpoint, so that they are
+// not sent again.
+const spoolRejectedSuffix = ".rejected"
+
+// spoolLockFile is the name of the lock file of the spool directories.
+const spoolLockFile = ".lock"
+
+// spoolMutex serializes the deliveries of the spooled events made by the
+// checks run concurrently by the `batch` and `agent` commands, so that an
+// event is not sent twice. The deliveries made by different processes are
+// serialized by lockFile.
+var spoolMutex sync.Mutex
+
+// eventRetryInterval is the time waited before the first retry; it is
+// doubled at every subsequent retry.
+var eventRetryInterval = 500 * time.Millisecond
+
+// sensuCheckName matches the characters not allowed in the Sensu check names.
+var sensuCheckName = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// checkResult is the result of a check, as reported by the event emitters.
+type checkResult struct {
+	Service   string     `json:"service"`
+	Command   string     `json:"command"`
+	State     int        `json:"state"`
+	StateName string     `json:"state_name"`
+	Message   string     `json:"message"`
+	Details   []string   `json:"details,omitempty"`
+	PerfData  []PerfData `json:"perfdata,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// spooledEvent is an event that could not be posted.
+type spooledEvent struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// eventRejectedError is returned when the endpoint rejects an event with a
+// client error, which would be returned again if the event were resent.
+type eventRejectedError struct {
+	url    string
+	status string
+}
+
+func (e *eventRejectedError) Error() string {
+	return fmt.Sprintf("%s rejected the event: %s", e.url, e.status)
+}
+
+// isEventRejected reports whether err is an eventRejectedError.
+func isEventRejected(err error) bool {
+	var rejected *eventRejectedError
+	return errors.As(err, &rejected)
+}
+
+// eventEmitter posts the events to an HTTP endpoint, retrying on failure
+// and spooling the events that cannot be delivered.
+type eventEmitter struct {
+	url      string
+	retries  int
+	spoolDir string
+	client   *http.Client
+}
+
+// newEventEmitter returns an event emitter posting to url.
+func newEventEmitter(url string, retries int, spoolDir string) *eventEmitter {
+	return &eventEmitter{
+		url:      url,
+		retries:  retries,
+		spoolDir: spoolDir,
+		client:   &http.Client{Timeout: eventTimeout},
+	}
+}
+
+// post sends a single event.
+func (e *eventEmitter) post(event spooledEvent) error {
+	resp, err := e.client.Post(event.URL, event.ContentType, bytes.NewReader(event.Body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	// the client errors other than timeouts and rate limits are permanent
+	if resp.StatusCode >= 400 && resp.StatusCode <= 499 &&
+		resp.StatusCode != http.StatusRequestTimeout &&
+		resp.StatusCode != http.StatusTooManyRequests {
+		return &eventRejectedError{url: event.URL, status: resp.Status}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", event.URL, resp.Status)
+	}
+	return nil
+}
+
+// postWithRetries sends a single event, retrying with an exponential backoff.
+// The events rejected by the endpoint are not retried.
+func (e *eventEmitter) postWithRetries(event spooledEvent) error {
+	interval := eventRetryInterval
+	err := e.post(event)
+	for i := 0; err != nil && !isEventRejected(err) && i < e.retries; i++ {
+		time.Sleep(interval)
+		interval *= 2
+		err = e.post(event)
+	}
+	return err
+}
+
+// flushSpool sends the spooled events, oldest first, and stops at the first
+// failure. The spooled events that cannot be decoded or that are rejected by
+// the endpoint are renamed with the spoolRejectedSuffix, so that they do not
+// block the delivery of the following ones.
+func (e *eventEmitter) flushSpool() error {
+	spoolMutex.Lock()
+	defer spoolMutex.Unlock()
+
+	unlock, err := lockFile(filepath.Join(e.spoolDir, spoolLockFile))
+	if err != nil {
+		return fmt.Errorf("error locking the spool directory: %s", err)
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(e.spoolDir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(e.spoolDir, name)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var event spooledEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			if err := os.Rename(path, path+spoolRejectedSuffix); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.post(event); err != nil {
+			if !isEventRejected(err) {
+				return err
+			}
+			if err := os.Rename(path, path+spoolRejectedSuffix); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spool stores the event in the spool directory.
+func (e *eventEmitter) spool(event spooledEvent) error {
+	name := fmt.Sprintf("%020d.json", time.Now().UnixNano())
+	return writeStateFile(filepath.Join(e.spoolDir, name), event)
+}
+
+// emit posts the event body. When a spool directory is configured, the
+// spooled events are sent first, and the event is spooled if it cannot be
+// delivered, in order to be sent at the next run. The event is posted even
+// if the spooled events cannot be sent, and it is not spooled if it has been
+// rejected by the endpoint.
+func (e *eventEmitter) emit(contentType string, body []byte) error {
+	event := spooledEvent{URL: e.url, ContentType: contentType, Body: body}
+
+	if e.spoolDir == "" {
+		return e.postWithRetries(event)
+	}
+
+	ferr := e.flushSpool()
+	err := e.postWithRetries(event)
+	switch {
+	case err == nil && ferr == nil:
+		return nil
+	case err == nil:
+		return fmt.Errorf("the event has been sent but not the spooled ones: %s", ferr)
+	case isEventRejected(err):
+		return err
+	}
+	if serr := e.spool(event); serr != nil {
+		return fmt.Errorf("%s (and the event cannot be spooled: %s)", err, serr)
+	}
+	return fmt.Errorf("%s (the event has been spooled)", err)
+}
+
+// sensuEvent returns a Sensu Go event, in the format accepted by the events
+// API of the Sensu agent. The performance data is reported in the Nagios
+// format.
+func sensuEvent(r checkResult) ([]byte, error) {
+	output := r.Message
+	if len(r.PerfData) > 0 {
+		metrics := make([]string, len(r.PerfData))
+		for i, p := range r.PerfData {
+			metrics[i] = p.String()
+		}
+		summary, long, multiline := strings.Cut(output, "\n")
+		output = summary + " | " + strings.Join(metrics, " ")
+		if multiline {
+			output += "\n" + long
+		}
+	}
+	if len(r.Details) > 0 {
+		output += "\n" + strings.Join(r.Details, "\n")
+	}
+
+	check := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": strings.Trim(sensuCheckName.ReplaceAllString(strings.ToLower(r.Service), "-"), "-"),
+		},
+		"status":   r.State,
+		"output":   output,
+		"issued":   r.Timestamp.Unix(),
+		"executed": r.Timestamp.Unix(),
+	}
+	if len(r.PerfData) > 0 {
+		check["output_metric_format"] = "nagios_perfdata"
+	}
+
+	return json.Marshal(map[string]interface{}{"check": check})
+}
+
+// webhookBody returns the body of a webhook event: the result of the check
+// in JSON or, if a template is given, the template evaluated against it.
+func webhookBody(r checkResult, tmpl *template.Template) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(r)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadEventTemplate parses the webhook template in the file at path.
+// The template function `json` returns the JSON encoding of its argument.
+func loadEventTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+	return template.New(filepath.Base(path)).Funcs(funcs).Parse(string(data))
+}
+
+// eventOutputter returns the Outputter posting the check results as Sensu
+// (format "sensu") or generic webhook (format "webhook") events. The results
+// are also displayed as in the default output format.
+func (c *BaseCommand) eventOutputter(format string) (*Outputter, error) {
+	url := c.EventURL
+	if url == "" {
+		if format != "sensu" {
+			return nil, fmt.Errorf("the %s output format requires the -event-url flag", format)
+		}
+		url = DefaultSensuURL
+	}
+
+	var tmpl *template.Template
+	if c.EventTemplate != "" {
+		if format != "webhook" {
+			return nil, fmt.Errorf("the -event-template flag requires the webhook output format")
+		}
+		var err error
+		if tmpl, err = loadEventTemplate(c.EventTemplate); err != nil {
+			return nil, fmt.Errorf("error loading the event template: %s", err)
+		}
+	}
+
+	if c.SpoolDir != "" {
+		if err := os.MkdirAll(c.SpoolDir, 0700); err != nil {
+			return nil, fmt.Errorf("error creating the spool directory: %s", err)
+		}
+	}
+
+	emitter := newEventEmitter(url, c.EventRetries, c.SpoolDir)
+
+	o := &Outputter{}
+	event := func(state int, display func(string)) func(format string, a ...interface{}) {
+		return func(f string, a ...interface{}) {
+			message := o.message(state, f, a...)
+			display(message + o.formatDetails())
+
+			result := o.result(c, state, message)
+
+			var body []byte
+			var err error
+			if format == "sensu" {
+				body, err = sensuEvent(result)
+			} else {
+				body, err = webhookBody(result, tmpl)
+			}
+			if err == nil {
+				err = emitter.emit("application/json", body)
+			}
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("error sending the %s event: %s", format, err))
+			}
+		}
+	}
+
+	o.Output = event(StateOk, c.UI.Output)
+	o.Warning = event(StateWarning, c.UI.Warn)
+	o.Critical = event(StateCritical, c.UI.Error)
+	o.Undefined = event(StateUndefined, c.UI.Error)
+	return o, nil
+}
@@ -0,0 +1,337 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+// testEventServer returns an HTTP server recording the bodies of the
+// requests, that fails while *down is true.
+func testEventServer(t *testing.T, down *bool) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if *down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, bodies...)
+	}
+}
+
+// spooledNames returns the names of the files in the spool directory, but
+// its lock file.
+func spooledNames(t *testing.T, spoolDir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != spoolLockFile {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestSensuEvent(t *testing.T) {
+	result := checkResult{
+		Service:   "Vault token (ci)",
+		State:     StateWarning,
+		Message:   "the token will expire soon",
+		Details:   []string{"[WARNING] the token is renewable"},
+		PerfData:  []PerfData{{Label: "ttl", Value: 3600, UOM: "s"}},
+		Timestamp: time.Unix(1760000000, 0),
+	}
+
+	body, err := sensuEvent(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"check":{"executed":1760000000,"issued":1760000000,` +
+		`"metadata":{"name":"vault-token-ci"},` +
+		`"output":"the token will expire soon | ttl=3600s\n[WARNING] the token is renewable",` +
+		`"output_metric_format":"nagios_perfdata","status":1}}`
+	if string(body) != expected {
+		t.Errorf("expected %s to be %s", body, expected)
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.tmpl")
+	content := `{"text": {{ json (printf "%s: %s" .StateName .Message) }}, "service": {{ json .Service }}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadEventTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := webhookBody(checkResult{
+		Service:   "Vault status",
+		StateName: "CRITICAL",
+		Message:   `Vault "prod" is sealed!`,
+	}, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"text": "CRITICAL: Vault \"prod\" is sealed!", "service": "Vault status"}`
+	if string(body) != expected {
+		t.Errorf("expected %s to be %s", body, expected)
+	}
+}
+
+func TestEventEmitter_Spool(t *testing.T) {
+	down := true
+	server, bodies := testEventServer(t, &down)
+
+	spoolDir := t.TempDir()
+	emitter := newEventEmitter(server.URL, 0, spoolDir)
+
+	for _, body := range []string{"first", "second"} {
+		err := emitter.emit("text/plain", []byte(body))
+		if err == nil || !strings.Contains(err.Error(), "the event has been spooled") {
+			t.Errorf("expected the event %s to be spooled, got: %v", body, err)
+		}
+	}
+
+	down = false
+	if err := emitter.emit("text/plain", []byte("third")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := strings.Join(bodies(), ","), "first,second,third"; got != expected {
+		t.Errorf("expected the events %s to be %s", got, expected)
+	}
+	if names := spooledNames(t, spoolDir); len(names) != 0 {
+		t.Errorf("expected the spool directory to be empty, found %d files", len(names))
+	}
+}
+
+func TestEventEmitter_ConcurrentFlush(t *testing.T) {
+	down := true
+	server, bodies := testEventServer(t, &down)
+
+	spoolDir := t.TempDir()
+	for _, body := range []string{"first", "second", "third"} {
+		if err := newEventEmitter(server.URL, 0, spoolDir).emit("text/plain", []byte(body)); err == nil {
+			t.Fatalf("expected the event %s to be spooled", body)
+		}
+	}
+	down = false
+
+	// the checks run concurrently by the `batch` and `agent` commands must
+	// not send the spooled events twice
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf("concurrent-%d", i)
+			if err := newEventEmitter(server.URL, 0, spoolDir).emit("text/plain", []byte(body)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sent := make(map[string]int)
+	for _, body := range bodies() {
+		sent[body]++
+	}
+	for _, body := range []string{"first", "second", "third"} {
+		if sent[body] != 1 {
+			t.Errorf("expected the event %s to be sent once, got %d", body, sent[body])
+		}
+	}
+	if names := spooledNames(t, spoolDir); len(names) != 0 {
+		t.Errorf("expected the spool directory to be empty, found %s", strings.Join(names, ","))
+	}
+}
+
+func TestEventEmitter_Rejected(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if string(body) == "invalid" {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	spoolDir := t.TempDir()
+	emitter := newEventEmitter(server.URL, 3, spoolDir)
+
+	// a corrupted spool file and an event rejected by the endpoint must not
+	// block the delivery of the following events
+	if err := os.WriteFile(filepath.Join(spoolDir, "1.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStateFile(filepath.Join(spoolDir, "2.json"),
+		spooledEvent{URL: server.URL, ContentType: "text/plain", Body: []byte("invalid")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStateFile(filepath.Join(spoolDir, "3.json"),
+		spooledEvent{URL: server.URL, ContentType: "text/plain", Body: []byte("spooled")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := emitter.emit("text/plain", []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rejected events are neither retried nor spooled
+	err := emitter.emit("text/plain", []byte("invalid"))
+	if err == nil || !strings.Contains(err.Error(), "rejected the event: 400 Bad Request") {
+		t.Errorf("expected the event to be rejected, got: %v", err)
+	}
+
+	mu.Lock()
+	got := strings.Join(bodies, ",")
+	mu.Unlock()
+	if expected := "invalid,spooled,first,invalid"; got != expected {
+		t.Errorf("expected the events %s to be %s", got, expected)
+	}
+
+	got = strings.Join(spooledNames(t, spoolDir), ",")
+	if expected := "1.json.rejected,2.json.rejected"; got != expected {
+		t.Errorf("expected the spool directory to hold %s, found %s", expected, got)
+	}
+}
+
+func TestOutputHandle_Events(t *testing.T) {
+	down := false
+	server, bodies := testEventServer(t, &down)
+
+	cases := []struct {
+		name     string
+		format   string
+		url      string
+		spoolDir string
+		shouldbe string
+		fail     bool
+	}{
+		{
+			"sensu",
+			"sensu",
+			server.URL,
+			"",
+			`"metadata":{"name":"vault-status"},"output":"Vault (prod) is sealed!","status":2}}`,
+			false,
+		},
+		{
+			"webhook",
+			"webhook",
+			server.URL,
+			"",
+			`{"service":"Vault status","command":"status","state":2,"state_name":"CRITICAL",` +
+				`"message":"Vault (prod) is sealed!","timestamp":`,
+			false,
+		},
+		{
+			"webhook_spool",
+			"webhook",
+			server.URL,
+			filepath.Join(t.TempDir(), "spool"),
+			`"message":"Vault (prod) is sealed!"`,
+			false,
+		},
+		{
+			"webhook_without_url",
+			"webhook",
+			"",
+			"",
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &BaseCommand{
+				UI:           ui,
+				CommandName:  "status",
+				OutputFormat: tc.format,
+				EventURL:     tc.url,
+				SpoolDir:     tc.spoolDir,
+			}
+
+			out, err := c.OutputHandle()
+			if (err != nil) != tc.fail {
+				t.Fatalf("unexpected error value: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			out.Critical("Vault (%s) is sealed!", "prod")
+
+			if combined := ui.ErrorWriter.String(); combined != "Vault (prod) is sealed!\n" {
+				t.Errorf("unexpected output: %q", combined)
+			}
+
+			if tc.spoolDir != "" {
+				if info, err := os.Stat(tc.spoolDir); err != nil || !info.IsDir() {
+					t.Errorf("expected the spool directory %s to be created", tc.spoolDir)
+				}
+			}
+
+			events := bodies()
+			if len(events) == 0 || !strings.Contains(events[len(events)-1], tc.shouldbe) {
+				t.Errorf("expected the events %q to contain %q", events, tc.shouldbe)
+			}
+		})
+	}
+}
@@ -27,6 +27,13 @@ const (
 	getFieldDescr   = "Print only the field with the given name"
 )
 
+// getOutcomes are the outcomes of the `get` command.
+var getOutcomes = []checkOutcome{
+	{"found", StateOk, "the field of the secret has been read"},
+	{"missing-field", StateUndefined, "the field is not present in the secret (KV version 2)"},
+	{"missing-value", StateCritical, "the field is not present in the secret (KV version 1), or the secret has no data"},
+}
+
 // GetCommand is a CLI Command that holds the attributes of the command `readsecret`.
 type GetCommand struct {
 	*BaseCommand
@@ -59,15 +66,24 @@ Usage: hashicorp-vault-monitor get [options] -field FIELD KEY
        Specify a token for authentication. This can also be specified via the
        VAULT_TOKEN environment variable.
 
-    -output=<string>
-       Specify an output format. Can be 'default' or 'nagios'.
+%s
+
+    -format=<string>
+       Go text/template building the output message in place of the default
+       one. The template is evaluated against the fields .Path, .Field and
+       .Value (empty if the field is not found), the default message .Message
+       and the label of the state .State. For instance:
+
+         -format='{{.State}}: {{.Path}} is readable (runbook: https://wiki.example.com/kv)'
+
+%s
 
   Mandatory Options:
 
     -field=<string>
        Print only the field with the given name.
 
-  The exit code reflects the result of the read operation:
+  By default, the exit code reflects the result of the read operation:
 
       - %d - the secret has been successfully read
       - %d - the secret cannot be found of read
@@ -76,6 +92,8 @@ Usage: hashicorp-vault-monitor get [options] -field FIELD KEY
   For a full list of examples, please see the online documentation.
 `
 	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("missing-field=critical", getOutcomes),
 		StateOk, StateCritical, StateUndefined)
 }
 
@@ -86,7 +104,9 @@ func (c *GetCommand) Run(args []string) int {
 	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
 	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
 	cmdFlags.StringVar(&c.Field, "field", "", getFieldDescr)
-	cmdFlags.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.MessageTemplate, "format", "", messageTemplateDescr)
+	c.addStateFlag(cmdFlags, getOutcomes)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(err.Error())
@@ -124,13 +144,18 @@ func (c *GetCommand) Run(args []string) int {
 
 	secret, err := client.Logical().Read(c.Path)
 	if err != nil {
-		out.Undefined("error reading %s: %s", c.Path, err)
-		return StateUndefined
+		return c.report(out, "error", "error reading %s: %s", c.Path, err)
 	}
 	if secret == nil {
-		out.Undefined("no data found at %s", c.Path)
-		return StateUndefined
+		return c.report(out, "error", "no data found at %s", c.Path)
+	}
+
+	result := map[string]interface{}{
+		"Path":  c.Path,
+		"Field": c.Field,
+		"Value": "",
 	}
+	out.SetData(result)
 
 	// secret.Data in KVv2 is an object of type map[string]interface{} with two entries:
 	// - data -> map[foo:bar]
@@ -141,16 +166,15 @@ func (c *GetCommand) Run(args []string) int {
 	if data, ok := secret.Data["data"]; ok && data != nil {
 		val := data.(map[string]interface{})[c.Field]
 		if val == nil {
-			out.Undefined("field '%s' not present in secret '%s'", c.Field, c.Path)
-			return StateUndefined
+			return c.report(out, "missing-field", "field '%s' not present in secret '%s'", c.Field, c.Path)
 		}
-		out.Output("found a value for the key %s: '%v'", c.Field, val)
-		return StateOk
+		result["Value"] = val
+		return c.report(out, "found", "found a value for the key %s: '%v'", c.Field, val)
 	} else if val, ok := secret.Data[c.Field]; ok && val != nil {
-		out.Output("found value: '%v'", val)
-		return StateOk
+		result["Value"] = val
+		return c.report(out, "found", "found value: '%v'", val)
 	}
 
-	out.Critical("field '%s' not present in secret '%s': %s", c.Field, c.Path, strings.Join(secret.Warnings, " "))
-	return StateCritical
+	return c.report(out, "missing-value", "field '%s' not present in secret '%s': %s",
+		c.Field, c.Path, strings.Join(secret.Warnings, " "))
 }
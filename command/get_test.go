@@ -67,6 +67,30 @@ func TestGetCommand_Run(t *testing.T) {
 			"bar",
 			StateOk,
 		},
+		{
+			"missing_field",
+			[]string{"-field", "nosuchfield", "secret/test"},
+			"field 'nosuchfield' not present in secret 'secret/test'",
+			StateCritical,
+		},
+		{
+			"missing_field_state",
+			[]string{"-field", "nosuchfield", "-state", "missing-value=warning", "secret/test"},
+			"field 'nosuchfield' not present in secret 'secret/test'",
+			StateWarning,
+		},
+		{
+			"custom_format",
+			[]string{"-field", "foo", "-format", "{{.State}}: {{.Path}} {{.Field}}={{.Value}}", "secret/test"},
+			"OK: secret/test foo=bar",
+			StateOk,
+		},
+		{
+			"custom_format_missing_field",
+			[]string{"-field", "nosuchfield", "-format", "{{.State}}: {{.Field}}={{if .Value}}{{.Value}}{{else}}<none>{{end}}", "secret/test"},
+			"CRITICAL: nosuchfield=<none>",
+			StateCritical,
+		},
 		{
 			"nagios_not_enough_args",
 			[]string{"-output", "nagios"},
@@ -130,6 +154,38 @@ func TestGetCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("kv2_missing_field", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{
+			"/v1/kv/data/test": `{"data": {"data": {"foo": "bar"},
+				"metadata": {"version": 1}}}`,
+		})
+		defer closer()
+
+		for _, tc := range []struct {
+			args []string
+			code int
+		}{
+			{[]string{"-field", "nosuchfield", "kv/data/test"}, StateUndefined},
+			{[]string{"-field", "nosuchfield", "-state", "missing-field=critical", "kv/data/test"}, StateCritical},
+		} {
+			ui, cmd := testGetCommand(t, "", client)
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			expected := "field 'nosuchfield' not present in secret 'kv/data/test'"
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -19,11 +19,160 @@ package command
 import (
 	"flag"
 	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	haStatusNodesDescr    = "Comma separated list of the addresses of the cluster nodes to check"
+	haStatusDiscoverDescr = "Discover the cluster nodes from the sys/ha-status endpoint"
 )
 
 // HAStatusCommand is a CLI Command that holds the attributes of the command `hastatus`.
 type HAStatusCommand struct {
 	*BaseCommand
+	Nodes    string
+	Discover bool
+}
+
+// haStatusOutcomes are the outcomes of the `hastatus` command.
+var haStatusOutcomes = []checkOutcome{
+	{"active", StateOk, "the node is the active node"},
+	{"standby", StateOk, "the node is a standby node"},
+	{"no-leader", StateWarning, "the node is a standby node but the active node is unknown"},
+	{"sealed", StateCritical, "the node (or a cluster node) is sealed"},
+	{"ha-disabled", StateCritical, "the node (or a cluster node) has HA disabled"},
+	{"leader-error", StateCritical, "the leader status cannot be read"},
+	{"healthy", StateOk, "the cluster is healthy"},
+	{"node-unreachable", StateCritical, "a cluster node is unreachable"},
+	{"no-active-node", StateCritical, "the cluster has no active node"},
+	{"multiple-active-nodes", StateCritical, "the cluster has more than one active node"},
+	{"leader-mismatch", StateCritical, "the cluster nodes disagree on the leader"},
+	{"version-mismatch", StateWarning, "the cluster nodes run different Vault versions"},
+}
+
+// haNodeStatus holds the HA status of a single node of a Vault cluster.
+type haNodeStatus struct {
+	Address       string
+	Err           error
+	Sealed        bool
+	Version       string
+	ClusterName   string
+	HAEnabled     bool
+	Active        bool
+	PerfStandby   bool
+	LeaderAddress string
+}
+
+// mode returns a short description of the node status.
+func (n *haNodeStatus) mode() string {
+	switch {
+	case n.Err != nil:
+		return "unreachable"
+	case n.Sealed:
+		return "sealed"
+	case !n.HAEnabled:
+		return "ha-disabled"
+	case n.Active:
+		return "active"
+	case n.PerfStandby:
+		return "perf-standby"
+	default:
+		return "standby"
+	}
+}
+
+// groupBy returns the given values along with the nodes they belong to,
+// formatted as "value (node1, node2)" and sorted by value.
+func groupBy(values map[string][]string) []string {
+	var groups []string
+	for value, nodes := range values {
+		groups = append(groups, fmt.Sprintf("%s (%s)", value, strings.Join(nodes, ", ")))
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// checkHACluster verifies that the cluster has exactly one active node, that
+// all the standby nodes agree on the leader, that no node is sealed and that
+// all the nodes run the same Vault version. It returns the resulting state,
+// as given by states for the detected outcomes, along with the list of the
+// detected problems.
+func checkHACluster(nodes []haNodeStatus, states func(outcome string) int) (int, []string) {
+	var problems []string
+	retCode := states("healthy")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, states(outcome))
+	}
+
+	var active []string
+	leaders := make(map[string][]string)
+	versions := make(map[string][]string)
+
+	for _, n := range nodes {
+		switch {
+		case n.Err != nil:
+			report("node-unreachable", "%s is unreachable", n.Address)
+			continue
+		case n.Sealed:
+			report("sealed", "%s is sealed", n.Address)
+		case !n.HAEnabled:
+			report("ha-disabled", "%s has HA disabled", n.Address)
+		default:
+			if n.Active {
+				active = append(active, n.Address)
+			}
+			leader := n.LeaderAddress
+			if leader == "" {
+				leader = "<none>"
+			}
+			leaders[leader] = append(leaders[leader], n.Address)
+		}
+		versions[n.Version] = append(versions[n.Version], n.Address)
+	}
+
+	switch len(active) {
+	case 1:
+	case 0:
+		report("no-active-node", "no active node")
+	default:
+		report("multiple-active-nodes", "%d active nodes: %s", len(active), strings.Join(active, ", "))
+	}
+
+	if len(leaders) > 1 {
+		report("leader-mismatch", "nodes disagree on the leader: %s",
+			strings.Join(groupBy(leaders), ", "))
+	}
+
+	if len(versions) > 1 {
+		report("version-mismatch", "inconsistent versions: %s",
+			strings.Join(groupBy(versions), ", "))
+	}
+
+	return retCode, problems
+}
+
+// addNodeDetails adds to the output one detail line per node, with the
+// state of the outcome detected for the node.
+func (c *HAStatusCommand) addNodeDetails(out *Outputter, nodes []haNodeStatus) {
+	for _, n := range nodes {
+		switch {
+		case n.Err != nil:
+			out.AddDetail(c.state("node-unreachable"), "%s: unreachable (%s)", n.Address, n.Err)
+		case n.Sealed:
+			out.AddDetail(c.state("sealed"), "%s: sealed, version %s", n.Address, n.Version)
+		case !n.HAEnabled:
+			out.AddDetail(c.state("ha-disabled"), "%s: ha-disabled, version %s", n.Address, n.Version)
+		default:
+			leader := n.LeaderAddress
+			if leader == "" {
+				leader = "<none>"
+			}
+			out.AddDetail(StateOk, "%s: %s, version %s, leader %s", n.Address, n.mode(), n.Version, leader)
+		}
+	}
 }
 
 // Synopsis returns a short synopsis of the `hastatus` command.
@@ -46,22 +195,54 @@ Usage: hashicorp-vault-monitor hastatus [options]
        Address of the Vault server. The default is https://127.0.0.1:8200. This
        can also be specified via the VAULT_ADDR environment variable.
 
-    -output=<string>
-       Specify an output format. Can be 'default' or 'nagios'.
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable. The token is needed by -discover.
+
+%s
+
+    -format=<string>
+       Go text/template building the output message in place of the default
+       one. The template is evaluated against the default message .Message,
+       the label of the state .State and the fields .ClusterName, .Sealed,
+       .Progress, .Threshold, .HAEnabled, .Active, .Mode ('active' or
+       'standby') and .LeaderAddress or, when checking all the nodes of the
+       cluster, .ClusterName, .Nodes (number of nodes), .ActiveNode and
+       .Problems. The function 'join' joins a list of strings:
+
+         -format='{{.Message}}{{if .Problems}} - problems: {{join .Problems ", "}}{{end}}'
+
+%s
 
     -sealed-as-warning
-       Sealed status is treated as warning.
+       Deprecated, same as -state sealed=warning.
 
-  The exit code reflects the seal status:
+    -nodes=<string>
+       Comma separated list of the addresses of the cluster nodes. All the
+       nodes are checked (see below) instead of the node at -address.
+
+    -discover
+       Check all the cluster nodes, as listed by the sys/ha-status endpoint
+       of the node at -address.
+
+  By default, the exit code reflects the HA status:
 
       - %d - the HA cluster is enabled and the node is active or in standby mode
       - %d - the HA cluster is enabled, the node is in standby mode but the active node is unknown
       - %d - the HA cluster node is not enabled
       - %d - an error occurred
 
+  When -nodes or -discover is set, all the nodes are queried concurrently and
+  the status of each node is reported on its own line. The cluster is then
+  critical if a node is unreachable, sealed or not in HA mode, if there is not
+  exactly one active node, or if the nodes disagree on the leader address,
+  and warning if the nodes run different Vault versions.
+
   For a full list of examples, please see the online documentation.
 `
 	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("sealed=warning,no-leader=critical,error=critical", haStatusOutcomes),
 		StateOk, StateWarning, StateCritical, StateUndefined)
 }
 
@@ -70,8 +251,13 @@ func (c *HAStatusCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("hastatus", flag.ContinueOnError)
 	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
 	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
-	cmdFlags.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.MessageTemplate, "format", "", messageTemplateDescr)
+	c.addStateFlag(cmdFlags, haStatusOutcomes)
 	cmdFlags.BoolVar(&c.SealedAsWarning, "sealed-as-warning", false, sealedAsWarningDescr)
+	cmdFlags.StringVar(&c.Nodes, "nodes", "", haStatusNodesDescr)
+	cmdFlags.BoolVar(&c.Discover, "discover", false, haStatusDiscoverDescr)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(err.Error())
@@ -90,6 +276,10 @@ func (c *HAStatusCommand) Run(args []string) int {
 		return StateUndefined
 	}
 
+	if c.Nodes != "" || c.Discover {
+		return c.runCluster(out)
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		out.Undefined(err.Error())
@@ -98,52 +288,103 @@ func (c *HAStatusCommand) Run(args []string) int {
 
 	status, err := client.Sys().SealStatus()
 	if err != nil {
-		out.Undefined("error checking seal status: %s", err)
-		return StateUndefined
+		return c.report(out, "error", "error checking seal status: %s", err)
 	}
 
+	out.AddPerfData(sealPerfData(status)...)
+	data := sealData(status)
+	out.SetData(data)
+
 	if status.Sealed {
-		if c.SealedAsWarning {
-			out.Warning("Vault (%s) is sealed! Unseal Progress: %d/%d",
-				status.ClusterName,
-				status.Progress,
-				status.T)
-			return StateWarning
-		} else {
-			out.Critical("Vault (%s) is sealed! Unseal Progress: %d/%d",
-				status.ClusterName,
-				status.Progress,
-				status.T)
-			return StateCritical
-		}
+		return c.report(out, "sealed", "Vault (%s) is sealed! Unseal Progress: %d/%d",
+			status.ClusterName,
+			status.Progress,
+			status.T)
 	}
 
 	leaderStatus, err := client.Sys().Leader()
 	if err != nil {
-		out.Critical("Error checking leader status: %s", err)
-		return StateCritical
+		return c.report(out, "leader-error", "Error checking leader status: %s", err)
 	}
 
+	data["HAEnabled"] = leaderStatus.HAEnabled
 	if !leaderStatus.HAEnabled {
-		out.Critical("Vault HA (%s) is not enabled", status.ClusterName)
-		return StateCritical
+		out.AddPerfData(PerfData{Label: "ha_enabled", Value: 0, Min: "0", Max: "1"})
+		return c.report(out, "ha-disabled", "Vault HA (%s) is not enabled", status.ClusterName)
 	}
 
 	modeInfo := "Active Node"
-	retCode := StateOk
+	retCode := c.state("active")
 
 	if !leaderStatus.IsSelf {
+		retCode = c.state("standby")
 		if leaderStatus.LeaderAddress == "" {
 			leaderStatus.LeaderAddress = "<none>"
-			retCode = StateWarning
+			retCode = c.state("no-leader")
 		}
 		modeInfo = fmt.Sprintf("Standby Node (Active Node Address: %s)",
 			leaderStatus.LeaderAddress)
 	}
 
-	out.Output("Vault HA (%s) is enabled, %s",
+	active := 0.0
+	data["Mode"] = "standby"
+	if leaderStatus.IsSelf {
+		active = 1
+		data["Mode"] = "active"
+	}
+	data["Active"] = leaderStatus.IsSelf
+	data["LeaderAddress"] = leaderStatus.LeaderAddress
+	out.AddPerfData(
+		PerfData{Label: "ha_enabled", Value: 1, Min: "0", Max: "1"},
+		PerfData{Label: "ha_active", Value: active, Min: "0", Max: "1"})
+
+	out.State(retCode)("Vault HA (%s) is enabled, %s",
 		status.ClusterName,
 		modeInfo)
 
 	return retCode
 }
+
+// runCluster checks the HA status of all the nodes of the cluster.
+func (c *HAStatusCommand) runCluster(out *Outputter) int {
+	addresses, err := c.clusterNodes(c.Nodes, c.Discover)
+	if err != nil {
+		return c.report(out, "error", "%s", err)
+	}
+
+	nodes := c.queryNodes(addresses)
+
+	retCode, problems := checkHACluster(nodes, c.state)
+
+	var clusterName, activeNode string
+	for _, n := range nodes {
+		if clusterName == "" {
+			clusterName = n.ClusterName
+		}
+		if n.Active && n.Err == nil {
+			activeNode = n.Address
+		}
+	}
+
+	out.SetData(map[string]interface{}{
+		"ClusterName": clusterName,
+		"Nodes":       len(nodes),
+		"ActiveNode":  activeNode,
+		"Problems":    problems,
+	})
+
+	c.addNodeDetails(out, nodes)
+
+	if len(problems) > 0 {
+		out.State(retCode)("Vault HA (%s) cluster: %s",
+			clusterName,
+			strings.Join(problems, "; "))
+		return retCode
+	}
+
+	out.State(retCode)("Vault HA (%s) cluster is healthy, %d nodes (Active Node Address: %s)",
+		clusterName,
+		len(nodes),
+		activeNode)
+	return retCode
+}
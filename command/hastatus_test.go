@@ -20,10 +20,16 @@
 package command
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/helper/builtinplugins"
+	"github.com/hashicorp/vault/vault"
 	"github.com/mitchellh/cli"
+
+	vaulthttp "github.com/hashicorp/vault/http"
 )
 
 func testHAStatusCommand(t *testing.T) (*cli.MockUi, *HAStatusCommand) {
@@ -35,6 +41,128 @@ func testHAStatusCommand(t *testing.T) (*cli.MockUi, *HAStatusCommand) {
 	}
 }
 
+func TestCheckHACluster(t *testing.T) {
+	active := haNodeStatus{Address: "https://node1:8200", Version: "1.19.0",
+		HAEnabled: true, Active: true, LeaderAddress: "https://node1:8200"}
+	standby := haNodeStatus{Address: "https://node2:8200", Version: "1.19.0",
+		HAEnabled: true, LeaderAddress: "https://node1:8200"}
+
+	cases := []struct {
+		name   string
+		nodes  []haNodeStatus
+		states stateFlag
+		code   int
+		out    string
+	}{
+		{
+			"healthy",
+			[]haNodeStatus{active, standby},
+			nil,
+			StateOk,
+			"",
+		},
+		{
+			"no_active_node",
+			[]haNodeStatus{standby},
+			nil,
+			StateCritical,
+			"no active node",
+		},
+		{
+			"two_active_nodes",
+			[]haNodeStatus{active, {Address: "https://node2:8200", Version: "1.19.0",
+				HAEnabled: true, Active: true, LeaderAddress: "https://node2:8200"}},
+			nil,
+			StateCritical,
+			"2 active nodes: https://node1:8200, https://node2:8200",
+		},
+		{
+			"leader_disagreement",
+			[]haNodeStatus{active, standby, {Address: "https://node3:8200", Version: "1.19.0",
+				HAEnabled: true, LeaderAddress: "https://node4:8200"}},
+			nil,
+			StateCritical,
+			"nodes disagree on the leader: https://node1:8200 (https://node1:8200, https://node2:8200), https://node4:8200 (https://node3:8200)",
+		},
+		{
+			"sealed_node",
+			[]haNodeStatus{active, {Address: "https://node2:8200", Version: "1.19.0", Sealed: true}},
+			nil,
+			StateCritical,
+			"https://node2:8200 is sealed",
+		},
+		{
+			"sealed_node_as_warning",
+			[]haNodeStatus{active, {Address: "https://node2:8200", Version: "1.19.0", Sealed: true}},
+			stateFlag{"sealed": StateWarning},
+			StateWarning,
+			"https://node2:8200 is sealed",
+		},
+		{
+			"unreachable_node",
+			[]haNodeStatus{active, standby, {Address: "https://node3:8200", Err: errors.New("timeout")}},
+			nil,
+			StateCritical,
+			"https://node3:8200 is unreachable",
+		},
+		{
+			"inconsistent_versions",
+			[]haNodeStatus{active, {Address: "https://node2:8200", Version: "1.18.5",
+				HAEnabled: true, LeaderAddress: "https://node1:8200"}},
+			nil,
+			StateWarning,
+			"inconsistent versions: 1.18.5 (https://node2:8200), 1.19.0 (https://node1:8200)",
+		},
+		{
+			"inconsistent_versions_as_critical",
+			[]haNodeStatus{active, {Address: "https://node2:8200", Version: "1.18.5",
+				HAEnabled: true, LeaderAddress: "https://node1:8200"}},
+			stateFlag{"version-mismatch": StateCritical},
+			StateCritical,
+			"inconsistent versions: 1.18.5 (https://node2:8200), 1.19.0 (https://node1:8200)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &BaseCommand{States: tc.states}
+			c.outcomes = haStatusOutcomes
+			code, problems := checkHACluster(tc.nodes, c.state)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d (%v)", code, tc.code, problems)
+			}
+
+			combined := strings.Join(problems, "; ")
+			if tc.out == "" && combined != "" {
+				t.Errorf("expected no problems, got %q", combined)
+			}
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestQueryNodes(t *testing.T) {
+	// no client has been created yet: the goroutines querying the nodes
+	// must share a single one (run with -race)
+	base := &BaseCommand{UI: cli.NewMockUi()}
+	addresses := []string{"http://127.0.0.1:1", "http://127.0.0.1:2", "http://127.0.0.1:3"}
+
+	nodes := base.queryNodes(addresses)
+	for i, n := range nodes {
+		if n.Address != addresses[i] {
+			t.Errorf("expected %s to be %s", n.Address, addresses[i])
+		}
+		if n.Err == nil {
+			t.Errorf("expected an error for the unreachable node %s", n.Address)
+		}
+	}
+	if base.client == nil {
+		t.Errorf("expected the client to be created")
+	}
+}
+
 func TestHAStatusCommand_Run(t *testing.T) {
 	t.Parallel()
 
@@ -74,6 +202,24 @@ func TestHAStatusCommand_Run(t *testing.T) {
 			" is enabled, Active Node",
 			StateOk,
 		},
+		{
+			"custom_format",
+			[]string{"-format", "{{.State}}: {{.Mode}} node{{if .HAEnabled}}, HA enabled{{end}}"},
+			"OK: active node, HA enabled",
+			StateOk,
+		},
+		{
+			"active_as_warning",
+			[]string{"-state", "active=warning"},
+			" is enabled, Active Node",
+			StateWarning,
+		},
+		{
+			"unknown_outcome",
+			[]string{"-state", "unsealed=warning"},
+			"Unknown outcome: unsealed",
+			StateUndefined,
+		},
 	}
 
 	t.Run("status", func(t *testing.T) {
@@ -120,4 +266,129 @@ func TestHAStatusCommand_Run(t *testing.T) {
 			t.Errorf("expected %q to contain %q", combined, expected)
 		}
 	})
+
+	t.Run("communication_failure_state", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testHAStatusCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-state", "error=critical"})
+		if exp := StateCritical; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error checking seal status: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		t.Parallel()
+
+		cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+			CredentialBackends: defaultVaultCredentialBackends,
+			AuditBackends:      defaultVaultAuditBackends,
+			LogicalBackends:    defaultVaultLogicalBackends,
+			BuiltinRegistry:    builtinplugins.Registry,
+		}, &vault.TestClusterOptions{
+			HandlerFunc: vaulthttp.Handler,
+			NumCores:    3,
+		})
+		cluster.Start()
+		defer cluster.Cleanup()
+
+		vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+		client := cluster.Cores[0].Client
+		client.SetToken(cluster.RootToken)
+
+		// wait for all the standby nodes to be listed by sys/ha-status
+		for i := 0; ; i++ {
+			status, err := client.Sys().HAStatus()
+			if err == nil && len(status.Nodes) == len(cluster.Cores) {
+				break
+			}
+			if i == 50 {
+				t.Fatalf("standby nodes not listed by sys/ha-status: %v", err)
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		var addresses []string
+		for _, core := range cluster.Cores {
+			addresses = append(addresses, core.Client.Address())
+		}
+
+		clusterCases := []struct {
+			name string
+			args []string
+			out  string
+			code int
+		}{
+			{
+				"nodes",
+				[]string{"-nodes", strings.Join(addresses, ",")},
+				"cluster is healthy, 3 nodes (Active Node Address: " + addresses[0] + ")",
+				StateOk,
+			},
+			{
+				"discover",
+				[]string{"-discover"},
+				"cluster is healthy, 3 nodes",
+				StateOk,
+			},
+			{
+				"unreachable_node",
+				[]string{"-nodes", addresses[0] + ",https://127.0.0.1:1"},
+				"https://127.0.0.1:1 is unreachable",
+				StateCritical,
+			},
+			{
+				"custom_format",
+				[]string{"-nodes", strings.Join(addresses, ","), "-format",
+					"{{.State}}: {{.Nodes}} nodes, active node {{.ActiveNode}}{{if .Problems}}, problems: {{join .Problems \", \"}}{{end}}"},
+				"OK: 3 nodes, active node " + addresses[0] + "\n",
+				StateOk,
+			},
+			{
+				"custom_format_problems",
+				[]string{"-nodes", addresses[0] + ",https://127.0.0.1:1", "-format",
+					"{{.State}}: {{.Nodes}} nodes, active node {{.ActiveNode}}{{if .Problems}}, problems: {{join .Problems \", \"}}{{end}}"},
+				"CRITICAL: 2 nodes, active node " + addresses[0] + ", problems: https://127.0.0.1:1 is unreachable",
+				StateCritical,
+			},
+			{
+				"unreachable_node_details",
+				[]string{"-nodes", addresses[0] + ",https://127.0.0.1:1"},
+				"\n[CRITICAL] https://127.0.0.1:1: unreachable (",
+				StateCritical,
+			},
+		}
+
+		for _, tc := range clusterCases {
+			t.Run(tc.name, func(t *testing.T) {
+				ui, cmd := testHAStatusCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+				if !strings.Contains(combined, "\n[OK] "+addresses[0]+": active") {
+					t.Errorf("expected %q to contain the status of the active node", combined)
+				}
+			})
+		}
+	})
 }
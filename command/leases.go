@@ -0,0 +1,459 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Default thresholds for the `leases` command.
+const (
+	DefaultLeasesWarning   = 10000
+	DefaultLeasesCritical  = 50000
+	DefaultLeasesMaxListed = 100000
+)
+
+const (
+	leasesSourceDescr         = "Where to count the leases: 'lookup' or 'metrics' (default: lookup)"
+	leasesPrefixDescr         = "Comma separated list of the lease prefixes to count"
+	leasesMaxListedDescr      = "Stop listing the leases after this number of leases (default: %d)"
+	leasesWarningDescr        = "Warning if the number of leases of a prefix reaches this value (default: %d)"
+	leasesCriticalDescr       = "Critical if the number of leases of a prefix reaches this value (default: %d)"
+	leasesGrowthWarningDescr  = "Warning if the leases of a prefix grow by this number per hour"
+	leasesGrowthCriticalDescr = "Critical if the leases of a prefix grow by this number per hour"
+	leasesStateFileDescr      = "File where the lease counts are saved for computing the growth rate"
+)
+
+// leasesNumLeasesGauge is the telemetry gauge of the number of leases.
+const leasesNumLeasesGauge = "vault.expire.num_leases"
+
+// leasesTotal is the prefix name used for the total number of leases
+// reported by the telemetry.
+const leasesTotal = "total"
+
+// LeasesCommand is a CLI Command that holds the attributes of the command `leases`.
+type LeasesCommand struct {
+	*BaseCommand
+	Source         string
+	Prefixes       string
+	MaxListed      int
+	Warning        int
+	Critical       int
+	GrowthWarning  float64
+	GrowthCritical float64
+	StateFile      string
+}
+
+// leasesState is the data saved in the state file between two runs.
+type leasesState struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// leasesThresholds holds the thresholds used for checking the lease counts.
+type leasesThresholds struct {
+	warning        int
+	critical       int
+	growthWarning  float64
+	growthCritical float64
+}
+
+// leaseCounter counts the leases by recursively listing `sys/leases/lookup`,
+// up to a maximum number of leases.
+type leaseCounter struct {
+	client    *api.Client
+	remaining int
+	truncated bool
+}
+
+// list returns the keys found under the given lease prefix.
+func (lc *leaseCounter) list(prefix string) ([]string, error) {
+	secret, err := lc.client.Logical().List("sys/leases/lookup/" + prefix)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var data struct {
+		Keys []string `json:"keys"`
+	}
+	if err := decodeData(secret.Data, &data); err != nil {
+		return nil, err
+	}
+	return data.Keys, nil
+}
+
+// count returns the number of leases under the given prefix.
+func (lc *leaseCounter) count(prefix string) (int, error) {
+	keys, err := lc.list(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, key := range keys {
+		if lc.remaining <= 0 {
+			lc.truncated = true
+			break
+		}
+		if strings.HasSuffix(key, "/") {
+			n, err := lc.count(prefix + key)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			continue
+		}
+		total++
+		lc.remaining--
+	}
+	return total, nil
+}
+
+// prefixes returns the top level lease prefixes, with the auth methods
+// listed separately (e.g. "auth/token/", "database/").
+func (lc *leaseCounter) prefixes() ([]string, error) {
+	keys, err := lc.list("")
+	if err != nil {
+		return nil, err
+	}
+
+	var prefixes []string
+	for _, key := range keys {
+		if key != "auth/" {
+			prefixes = append(prefixes, key)
+			continue
+		}
+		methods, err := lc.list(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, method := range methods {
+			prefixes = append(prefixes, key+method)
+		}
+	}
+	return prefixes, nil
+}
+
+// leasesOutcomes are the outcomes of the `leases` command.
+var leasesOutcomes = []checkOutcome{
+	{"ok", StateOk, "the number of leases is below the thresholds"},
+	{"count-warning", StateWarning, "the number of leases reached -warning"},
+	{"count-critical", StateCritical, "the number of leases reached -critical"},
+	{"growth-warning", StateWarning, "the growth rate reached -growth-warning"},
+	{"growth-critical", StateCritical, "the growth rate reached -growth-critical"},
+	{"truncated", StateWarning, "the listing of the leases stopped at -max-listed"},
+}
+
+// sortedKeys returns the keys of the map m in sorted order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkLeases compares the lease counts with the thresholds and, if a
+// previous state is given, computes the growth rate of each prefix.
+// It returns the resulting state, as given by states for the detected
+// outcomes, along with the list of detected problems.
+func checkLeases(current, previous *leasesState, th leasesThresholds,
+	states func(outcome string) int) (int, []string) {
+	var problems []string
+	retCode := states("ok")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, states(outcome))
+	}
+
+	var elapsed time.Duration
+	if previous != nil {
+		elapsed = current.Timestamp.Sub(previous.Timestamp)
+	}
+
+	for _, prefix := range sortedKeys(current.Counts) {
+		count := current.Counts[prefix]
+		if th.critical > 0 && count >= th.critical {
+			report("count-critical", "%s has %d leases", prefix, count)
+		} else if th.warning > 0 && count >= th.warning {
+			report("count-warning", "%s has %d leases", prefix, count)
+		}
+
+		if elapsed <= 0 {
+			continue
+		}
+		last, ok := previous.Counts[prefix]
+		if !ok {
+			continue
+		}
+		rate := float64(count-last) / elapsed.Hours()
+		if th.growthCritical > 0 && rate >= th.growthCritical {
+			report("growth-critical", "%s leases grow by %.0f per hour", prefix, rate)
+		} else if th.growthWarning > 0 && rate >= th.growthWarning {
+			report("growth-warning", "%s leases grow by %.0f per hour", prefix, rate)
+		}
+	}
+
+	return retCode, problems
+}
+
+// Synopsis returns a short synopsis of the `leases` command.
+func (c *LeasesCommand) Synopsis() string {
+	return "Check the number of Vault leases"
+}
+
+// Help returns a long-form help text of the `leases` command.
+func (c *LeasesCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor leases [options]
+
+  This command counts the Vault leases of each mount (or of the given
+  prefixes) and checks their number and their growth rate.
+
+    $ hashicorp-vault-monitor leases -prefix database/creds/,auth/token/
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -source=<string>
+       Where to count the leases. Can be 'lookup' (default), for listing the
+       leases via 'sys/leases/lookup' (this requires a token with the 'sudo'
+       capability on this path), or 'metrics', for reading the total number
+       of leases from the '%s' telemetry gauge.
+
+    -prefix=<string>
+       Comma separated list of the lease prefixes to count. By default, the
+       leases of each secrets engine and auth method are counted separately.
+       Only used with '-source=lookup'.
+
+    -max-leases=<int>
+       Stop listing the leases after this number of leases (default: %d).
+       Only used with '-source=lookup'.
+
+    -warning=<int>
+       Warning if the number of leases of a prefix reaches this value
+       (default: %d).
+
+    -critical=<int>
+       Critical if the number of leases of a prefix reaches this value
+       (default: %d).
+
+    -growth-warning=<float>
+       Warning if the number of leases of a prefix grows by this value per
+       hour since the previous run. Requires '-state-file'.
+
+    -growth-critical=<float>
+       Critical if the number of leases of a prefix grows by this value per
+       hour since the previous run. Requires '-state-file'.
+
+    -state-file=<string>
+       File where the lease counts are saved for computing their growth
+       rate at the next run. The file is neither read nor updated when the
+       listing of the leases is truncated.
+
+  By default, the exit code reflects the lease counts:
+
+      - %d - the lease counts are below the thresholds
+      - %d - a lease count or growth rate reached the warning threshold, or
+            the listing of the leases has been truncated
+      - %d - a lease count or growth rate reached the critical threshold
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("growth-warning=ok,truncated=ok", leasesOutcomes),
+		leasesNumLeasesGauge,
+		DefaultLeasesMaxListed,
+		DefaultLeasesWarning,
+		DefaultLeasesCritical,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `leases` command with the given CLI instance and command-line arguments.
+func (c *LeasesCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("leases", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, leasesOutcomes)
+	cmdFlags.StringVar(&c.Source, "source", "lookup", leasesSourceDescr)
+	cmdFlags.StringVar(&c.Prefixes, "prefix", "", leasesPrefixDescr)
+	cmdFlags.IntVar(&c.MaxListed, "max-leases",
+		DefaultLeasesMaxListed,
+		fmt.Sprintf(leasesMaxListedDescr, DefaultLeasesMaxListed))
+	cmdFlags.IntVar(&c.Warning, "warning",
+		DefaultLeasesWarning,
+		fmt.Sprintf(leasesWarningDescr, DefaultLeasesWarning))
+	cmdFlags.IntVar(&c.Critical, "critical",
+		DefaultLeasesCritical,
+		fmt.Sprintf(leasesCriticalDescr, DefaultLeasesCritical))
+	cmdFlags.Float64Var(&c.GrowthWarning, "growth-warning", 0, leasesGrowthWarningDescr)
+	cmdFlags.Float64Var(&c.GrowthCritical, "growth-critical", 0, leasesGrowthCriticalDescr)
+	cmdFlags.StringVar(&c.StateFile, "state-file", "", leasesStateFileDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if c.Source != "lookup" && c.Source != "metrics" {
+		out.Undefined("unknown source of the leases: %s", c.Source)
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	current := &leasesState{
+		Timestamp: time.Now(),
+		Counts:    make(map[string]int),
+	}
+	truncated := false
+
+	if c.Source == "metrics" {
+		metrics, err := readMetrics(client)
+		if err != nil {
+			return c.report(out, "error", "error reading the Vault metrics: %s", err)
+		}
+		value, ok := metrics.gauge(leasesNumLeasesGauge)
+		if !ok {
+			return c.report(out, "error", "no %s gauge found in the Vault metrics", leasesNumLeasesGauge)
+		}
+		current.Counts[leasesTotal] = int(value)
+	} else {
+		counter := &leaseCounter{client: client, remaining: c.MaxListed}
+
+		var prefixes []string
+		if c.Prefixes != "" {
+			for _, prefix := range strings.Split(c.Prefixes, ",") {
+				prefix = strings.TrimSpace(prefix)
+				if !strings.HasSuffix(prefix, "/") {
+					prefix += "/"
+				}
+				prefixes = append(prefixes, prefix)
+			}
+		} else if prefixes, err = counter.prefixes(); err != nil {
+			return c.report(out, "error", "error listing the leases: %s", err)
+		}
+
+		for _, prefix := range prefixes {
+			// the following prefixes are not listed, rather than reported
+			// without leases
+			if counter.truncated {
+				break
+			}
+			n, err := counter.count(prefix)
+			if err != nil {
+				return c.report(out, "error", "error listing the leases of %s: %s", prefix, err)
+			}
+			current.Counts[prefix] = n
+		}
+		truncated = counter.truncated
+	}
+
+	// the partial counts of a truncated listing are neither compared with
+	// nor saved in the state file, which keeps the last complete counts
+	var previous *leasesState
+	if c.StateFile != "" && !truncated {
+		previous = new(leasesState)
+		found, err := readStateFile(c.StateFile, previous)
+		if err != nil {
+			return c.report(out, "error", "error reading the state file: %s", err)
+		}
+		if !found {
+			previous = nil
+		}
+		if err := writeStateFile(c.StateFile, current); err != nil {
+			return c.report(out, "error", "error writing the state file: %s", err)
+		}
+	}
+
+	retCode, problems := checkLeases(current, previous,
+		leasesThresholds{
+			warning:        c.Warning,
+			critical:       c.Critical,
+			growthWarning:  c.GrowthWarning,
+			growthCritical: c.GrowthCritical,
+		}, c.state)
+
+	if truncated {
+		problems = append(problems,
+			fmt.Sprintf("listing stopped after %d leases", c.MaxListed))
+		retCode = WorstState(retCode, c.state("truncated"))
+	}
+
+	if len(problems) > 0 {
+		out.State(retCode)("Vault leases: %s", strings.Join(problems, "; "))
+		return retCode
+	}
+
+	total := 0
+	var counts []string
+	for _, prefix := range sortedKeys(current.Counts) {
+		total += current.Counts[prefix]
+		if prefix != leasesTotal {
+			counts = append(counts, fmt.Sprintf("%s: %d", prefix, current.Counts[prefix]))
+		}
+	}
+
+	if len(counts) > 0 {
+		out.State(retCode)("Vault leases: %d (%s)", total, strings.Join(counts, ", "))
+	} else {
+		out.State(retCode)("Vault leases: %d", total)
+	}
+	return retCode
+}
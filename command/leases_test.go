@@ -0,0 +1,365 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+const testLeasesMetrics = `{
+  "Timestamp": "2026-10-18 10:00:00 +0000 UTC",
+  "Gauges": [
+    {"Name": "vault.expire.num_leases", "Value": 1234, "Labels": {}},
+    {"Name": "vault.runtime.num_goroutines", "Value": 150, "Labels": {}}
+  ]
+}`
+
+func testLeasesCommand(t *testing.T) (*cli.MockUi, *LeasesCommand) {
+	ui := cli.NewMockUi()
+	return ui, &LeasesCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+// testCreateLeases creates n tokens, each one with its own lease.
+func testCreateLeases(t *testing.T, client *api.Client, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		_, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{"default"},
+			TTL:      "1h",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckLeases(t *testing.T) {
+	now := time.Now()
+	th := leasesThresholds{
+		warning:        100,
+		critical:       1000,
+		growthWarning:  10,
+		growthCritical: 20,
+	}
+
+	previous := &leasesState{
+		Timestamp: now.Add(-2 * time.Hour),
+		Counts:    map[string]int{"auth/token/": 50, "database/": 50},
+	}
+
+	cases := []struct {
+		name     string
+		counts   map[string]int
+		previous *leasesState
+		code     int
+		out      string
+	}{
+		{
+			"below_thresholds",
+			map[string]int{"auth/token/": 50, "database/": 60},
+			previous,
+			StateOk,
+			"",
+		},
+		{
+			"count_warning",
+			map[string]int{"auth/token/": 50, "database/": 120},
+			nil,
+			StateWarning,
+			"database/ has 120 leases",
+		},
+		{
+			"count_critical",
+			map[string]int{"auth/token/": 1500, "database/": 120},
+			nil,
+			StateCritical,
+			"auth/token/ has 1500 leases; database/ has 120 leases",
+		},
+		{
+			"growth_warning",
+			map[string]int{"auth/token/": 50, "database/": 80},
+			previous,
+			StateWarning,
+			"database/ leases grow by 15 per hour",
+		},
+		{
+			"growth_critical",
+			map[string]int{"auth/token/": 50, "database/": 96},
+			previous,
+			StateCritical,
+			"database/ leases grow by 23 per hour",
+		},
+		{
+			"new_prefix",
+			map[string]int{"pki/": 90},
+			previous,
+			StateOk,
+			"",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := &leasesState{Timestamp: now, Counts: tc.counts}
+
+			code, problems := checkLeases(current, tc.previous, th,
+				(&BaseCommand{outcomes: leasesOutcomes}).state)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d (%v)", code, tc.code, problems)
+			}
+
+			combined := strings.Join(problems, "; ")
+			if tc.out == "" && combined != "" {
+				t.Errorf("expected no problems, got %q", combined)
+			}
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestLeasesCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor leases [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"bad_source",
+			[]string{"-source", "foo"},
+			"unknown source of the leases: foo",
+			StateUndefined,
+		},
+		{
+			"all_prefixes",
+			[]string{},
+			"Vault leases: 3 (auth/token/: 3)",
+			StateOk,
+		},
+		{
+			"prefix",
+			[]string{"-prefix", "auth/token"},
+			"Vault leases: 3 (auth/token/: 3)",
+			StateOk,
+		},
+		{
+			"empty_prefix",
+			[]string{"-prefix", "database/"},
+			"Vault leases: 0 (database/: 0)",
+			StateOk,
+		},
+		{
+			"count_warning",
+			[]string{"-warning", "3"},
+			"Vault leases: auth/token/ has 3 leases",
+			StateWarning,
+		},
+		{
+			"count_critical",
+			[]string{"-warning", "2", "-critical", "3"},
+			"Vault leases: auth/token/ has 3 leases",
+			StateCritical,
+		},
+		{
+			"truncated",
+			[]string{"-max-leases", "2"},
+			"listing stopped after 2 leases",
+			StateWarning,
+		},
+		{
+			"nagios_leases",
+			[]string{"-output", "nagios"},
+			"vault OK - Vault leases: 3",
+			StateOk,
+		},
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, _, closer := testVaultServerUnseal(t)
+				defer closer()
+
+				testCreateLeases(t, client, 3)
+
+				ui, cmd := testLeasesCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("growth", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		testCreateLeases(t, client, 3)
+
+		stateFile := filepath.Join(t.TempDir(), "leases.json")
+		previous := fmt.Sprintf(`{"timestamp":"%s","counts":{"auth/token/":1}}`,
+			time.Now().Add(-time.Hour).Format(time.RFC3339Nano))
+		if err := os.WriteFile(stateFile, []byte(previous), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testLeasesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-state-file", stateFile, "-growth-warning", "1"})
+		if exp := StateWarning; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "auth/token/ leases grow by 2 per hour"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		var saved leasesState
+		if _, err := readStateFile(stateFile, &saved); err != nil {
+			t.Fatal(err)
+		}
+		if saved.Counts["auth/token/"] != 3 {
+			t.Errorf("expected the state file to contain 3 leases, got %v", saved.Counts)
+		}
+	})
+
+	t.Run("growth_truncated", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		testCreateLeases(t, client, 3)
+
+		stateFile := filepath.Join(t.TempDir(), "leases.json")
+		previous := fmt.Sprintf(`{"timestamp":"%s","counts":{"auth/token/":1}}`,
+			time.Now().Add(-time.Hour).Format(time.RFC3339Nano))
+		if err := os.WriteFile(stateFile, []byte(previous), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testLeasesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-state-file", stateFile, "-growth-warning", "0.5",
+			"-growth-critical", "0.5", "-max-leases", "2"})
+		if exp := StateWarning; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, "listing stopped after 2 leases") || strings.Contains(combined, "grow") {
+			t.Errorf("expected %q to only report the truncated listing", combined)
+		}
+
+		data, err := os.ReadFile(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != previous {
+			t.Errorf("expected the state file not to be updated, got %s", data)
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{
+			"/v1/sys/metrics": testLeasesMetrics,
+		})
+		defer closer()
+
+		ui, cmd := testLeasesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-source", "metrics", "-warning", "1000"})
+		if exp := StateWarning; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Vault leases: total has 1234 leases"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testLeasesCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error listing the leases: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -42,98 +42,143 @@ const (
 	warningDescr  = "Warning threshold (default: %s)"
 	criticalDescr = "Critical threshold (default: %s)"
 
-	outputFormatDescr      = "Select an output format ('default' or 'nagios')"
-	unknownAsCriticalDescr = "Unknown status is always critical"
-	sealedAsWarningDescr   = "Sealed status is always warning"
+	outputFormatDescr      = "Select an output format (" + outputFormatsHelp + ")"
+	messageTemplateDescr   = "Go text/template building the output message from the result data of the command"
+	unknownAsCriticalDescr = "Deprecated, same as -state error=critical"
+	sealedAsWarningDescr   = "Deprecated, same as -state sealed=warning"
 )
 
-// Run initializes a CLI instance and its command state engine.
-func Run(args []string) int {
-	ui := &cli.BasicUi{
-		Reader:      os.Stdin,
-		Writer:      os.Stdout,
-		ErrorWriter: os.Stderr,
+// newBaseCommand returns the common options of the command name, writing
+// its output to ui.
+func newBaseCommand(ui cli.Ui, name string) *BaseCommand {
+	return &BaseCommand{
+		UI:           ui,
+		CommandName:  name,
+		OutputFormat: "default",
 	}
+}
 
-	verInfo := version.GetVersion()
-	version := verInfo.FullVersionNumber(true)
-
-	c := cli.NewCLI("hashicorp-vault-monitor", version)
-	c.Args = args
+// Commands returns the factories of all the monitoring commands, writing
+// their output to ui.
+func Commands(ui cli.Ui) map[string]cli.CommandFactory {
+	return commandFactories(func(name string) *BaseCommand {
+		return newBaseCommand(ui, name)
+	})
+}
 
-	c.Commands = map[string]cli.CommandFactory{
+// commandFactories returns the factories of all the monitoring commands,
+// with their common options set by the function base.
+func commandFactories(base func(name string) *BaseCommand) map[string]cli.CommandFactory {
+	return map[string]cli.CommandFactory{
+		"agent": func() (cli.Command, error) {
+			return &AgentCommand{
+				BaseCommand: base("agent"),
+			}, nil
+		},
+		"batch": func() (cli.Command, error) {
+			return &BatchCommand{
+				BaseCommand: base("batch"),
+			}, nil
+		},
+		"canary": func() (cli.Command, error) {
+			return &CanaryCommand{
+				BaseCommand: base("canary"),
+			}, nil
+		},
+		"discovery": func() (cli.Command, error) {
+			return &DiscoveryCommand{
+				BaseCommand: base("discovery"),
+			}, nil
+		},
 		"get": func() (cli.Command, error) {
 			return &GetCommand{
-				BaseCommand: &BaseCommand{
-					UI: &cli.ColoredUi{
-						Ui:          ui,
-						ErrorColor:  cli.UiColorRed,
-						InfoColor:   cli.UiColorNone,
-						OutputColor: cli.UiColorGreen,
-						WarnColor:   cli.UiColorYellow,
-					},
-					OutputFormat: "default",
-				},
+				BaseCommand: base("get"),
 			}, nil
 		},
 		"hastatus": func() (cli.Command, error) {
 			return &HAStatusCommand{
-				BaseCommand: &BaseCommand{
-					UI: &cli.ColoredUi{
-						Ui:          ui,
-						ErrorColor:  cli.UiColorRed,
-						InfoColor:   cli.UiColorNone,
-						OutputColor: cli.UiColorGreen,
-						WarnColor:   cli.UiColorYellow,
-					},
-					OutputFormat: "default",
-				},
+				BaseCommand: base("hastatus"),
+			}, nil
+		},
+		"leases": func() (cli.Command, error) {
+			return &LeasesCommand{
+				BaseCommand: base("leases"),
+			}, nil
+		},
+		"metric": func() (cli.Command, error) {
+			return &MetricCommand{
+				BaseCommand: base("metric"),
 			}, nil
 		},
 		"policies": func() (cli.Command, error) {
 			return &PoliciesCommand{
-				BaseCommand: &BaseCommand{
-					UI: &cli.ColoredUi{
-						Ui:          ui,
-						ErrorColor:  cli.UiColorRed,
-						InfoColor:   cli.UiColorNone,
-						OutputColor: cli.UiColorGreen,
-						WarnColor:   cli.UiColorYellow,
-					},
-					OutputFormat: "default",
-				},
+				BaseCommand: base("policies"),
+			}, nil
+		},
+		"probe": func() (cli.Command, error) {
+			return &ProbeCommand{
+				BaseCommand: base("probe"),
+			}, nil
+		},
+		"raft": func() (cli.Command, error) {
+			return &RaftCommand{
+				BaseCommand: base("raft"),
+			}, nil
+		},
+		"replication": func() (cli.Command, error) {
+			return &ReplicationCommand{
+				BaseCommand: base("replication"),
+			}, nil
+		},
+		"snapshot": func() (cli.Command, error) {
+			return &SnapshotCommand{
+				BaseCommand: base("snapshot"),
 			}, nil
 		},
 		"status": func() (cli.Command, error) {
 			return &StatusCommand{
-				BaseCommand: &BaseCommand{
-					UI: &cli.ColoredUi{
-						Ui:          ui,
-						ErrorColor:  cli.UiColorRed,
-						InfoColor:   cli.UiColorNone,
-						OutputColor: cli.UiColorGreen,
-						WarnColor:   cli.UiColorYellow,
-					},
-					OutputFormat: "default",
-				},
+				BaseCommand: base("status"),
 			}, nil
 		},
 		"token-lookup": func() (cli.Command, error) {
 			return &TokenLookupCommand{
-				BaseCommand: &BaseCommand{
-					UI: &cli.ColoredUi{
-						Ui:          ui,
-						ErrorColor:  cli.UiColorRed,
-						InfoColor:   cli.UiColorNone,
-						OutputColor: cli.UiColorGreen,
-						WarnColor:   cli.UiColorYellow,
-					},
-					OutputFormat: "default",
-				},
+				BaseCommand: base("token-lookup"),
 			}, nil
 		},
+		"transit-roundtrip": func() (cli.Command, error) {
+			return &TransitRoundtripCommand{
+				BaseCommand: base("transit-roundtrip"),
+			}, nil
+		},
+		"version": func() (cli.Command, error) {
+			return &VersionCommand{
+				BaseCommand: base("version"),
+			}, nil
+		},
+	}
+}
+
+// Run initializes a CLI instance and its command state engine.
+func Run(args []string) int {
+	ui := &cli.ColoredUi{
+		Ui: &cli.BasicUi{
+			Reader:      os.Stdin,
+			Writer:      os.Stdout,
+			ErrorWriter: os.Stderr,
+		},
+		ErrorColor:  cli.UiColorRed,
+		InfoColor:   cli.UiColorNone,
+		OutputColor: cli.UiColorGreen,
+		WarnColor:   cli.UiColorYellow,
 	}
 
+	verInfo := version.GetVersion()
+	version := verInfo.FullVersionNumber(true)
+
+	c := cli.NewCLI("hashicorp-vault-monitor", version)
+	c.Args = args
+	c.Commands = Commands(ui)
+
 	exitStatus, err := c.Run()
 
 	if err != nil {
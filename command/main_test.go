@@ -209,3 +209,55 @@ func testVaultServerBad(t testing.TB) (*api.Client, func()) {
 		}
 	}
 }
+
+// testVaultServerJSON creates an http server that replies to the requests
+// for the given API paths (e.g. "/v1/sys/health") with the associated JSON
+// payloads, and with a 404 to every other request.
+func testVaultServerJSON(t testing.TB, responses map[string]string) (*api.Client, func()) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := responses[r.URL.Path]
+			if !ok {
+				http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}),
+		ReadTimeout:       1 * time.Second,
+		ReadHeaderTimeout: 1 * time.Second,
+		WriteTimeout:      1 * time.Second,
+		IdleTimeout:       1 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+
+	client, err := api.NewClient(&api.Config{
+		Address: "http://" + listener.Addr().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("test-token")
+
+	return client, func() {
+		ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+
+		if err := server.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
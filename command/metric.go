@@ -0,0 +1,401 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	metricNameDescr     = "Name of the metric to check"
+	metricLabelDescr    = "Comma separated list of label=value filters"
+	metricFieldDescr    = "Field of a counter or summary metric to check (default: mean)"
+	metricFormatDescr   = "Format of the metrics to fetch: 'json' or 'prometheus' (default: json)"
+	metricWarningDescr  = "Warning threshold range (Nagios format)"
+	metricCriticalDescr = "Critical threshold range (Nagios format)"
+)
+
+// MetricCommand is a CLI Command that holds the attributes of the command `metric`.
+type MetricCommand struct {
+	*BaseCommand
+	Name          string
+	Labels        string
+	Field         string
+	MetricsFormat string
+	Warning       string
+	Critical      string
+}
+
+// metricSeries is a metric value along with its labels.
+type metricSeries struct {
+	labels map[string]string
+	value  float64
+}
+
+// parseLabelFilters parses a comma separated list of label=value filters.
+func parseLabelFilters(s string) (map[string]string, error) {
+	filters := make(map[string]string)
+	if s == "" {
+		return filters, nil
+	}
+	for _, filter := range strings.Split(s, ",") {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid label filter: '%s'", filter)
+		}
+		filters[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return filters, nil
+}
+
+// matchLabels returns true if labels contains all the given filters.
+func matchLabels(labels, filters map[string]string) bool {
+	for k, v := range filters {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels returns the labels in the form {k1=v1,k2=v2}.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sampleField returns the given field of a JSON counter or sample.
+func sampleField(s metricsSample, field string) (float64, error) {
+	switch field {
+	case "count":
+		return float64(s.Count), nil
+	case "rate":
+		return s.Rate, nil
+	case "sum":
+		return s.Sum, nil
+	case "min":
+		return s.Min, nil
+	case "max":
+		return s.Max, nil
+	case "", "mean":
+		return s.Mean, nil
+	case "stddev":
+		return s.Stddev, nil
+	}
+	return 0, fmt.Errorf("unknown field of the metric %s: %s", s.Name, field)
+}
+
+// selectJSONMetric returns the series of the JSON metrics named name
+// and matching the label filters.
+func selectJSONMetric(summary *metricsSummary, name, field string,
+	filters map[string]string) ([]metricSeries, error) {
+	var series []metricSeries
+
+	for _, g := range summary.Gauges {
+		if g.Name != name || !matchLabels(g.Labels, filters) {
+			continue
+		}
+		if field != "" && field != "value" {
+			return nil, fmt.Errorf("unknown field of the gauge %s: %s", name, field)
+		}
+		series = append(series, metricSeries{g.Labels, g.Value})
+	}
+
+	for _, samples := range [][]metricsSample{summary.Counters, summary.Samples} {
+		for _, s := range samples {
+			if s.Name != name || !matchLabels(s.Labels, filters) {
+				continue
+			}
+			value, err := sampleField(s, field)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, metricSeries{s.Labels, value})
+		}
+	}
+
+	return series, nil
+}
+
+// prometheusValue returns the given field of a Prometheus metric.
+// The fields of a summary are 'count', 'sum', 'mean' and its quantiles
+// (e.g. '0.99').
+func prometheusValue(family *dto.MetricFamily, m *dto.Metric, field string) (float64, error) {
+	switch family.GetType() {
+	case dto.MetricType_GAUGE, dto.MetricType_COUNTER, dto.MetricType_UNTYPED:
+		if field != "" && field != "value" {
+			break
+		}
+		switch {
+		case m.Gauge != nil:
+			return m.Gauge.GetValue(), nil
+		case m.Counter != nil:
+			return m.Counter.GetValue(), nil
+		case m.Untyped != nil:
+			return m.Untyped.GetValue(), nil
+		}
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		switch field {
+		case "count":
+			return float64(s.GetSampleCount()), nil
+		case "sum":
+			return s.GetSampleSum(), nil
+		case "", "mean":
+			if s.GetSampleCount() == 0 {
+				return 0, nil
+			}
+			return s.GetSampleSum() / float64(s.GetSampleCount()), nil
+		}
+		if quantile, err := strconv.ParseFloat(field, 64); err == nil {
+			for _, q := range s.GetQuantile() {
+				if q.GetQuantile() == quantile {
+					return q.GetValue(), nil
+				}
+			}
+			return 0, fmt.Errorf("no quantile %s for the metric %s", field, family.GetName())
+		}
+	}
+	return 0, fmt.Errorf("unknown field of the metric %s: %s", family.GetName(), field)
+}
+
+// selectPrometheusMetric returns the series of the Prometheus metric named
+// name and matching the label filters. The dots in name are converted into
+// underscores, so that the same metric names can be used for both formats.
+func selectPrometheusMetric(families map[string]*dto.MetricFamily, name, field string,
+	filters map[string]string) ([]metricSeries, error) {
+	family, ok := families[strings.ReplaceAll(name, ".", "_")]
+	if !ok {
+		return nil, nil
+	}
+
+	var series []metricSeries
+	for _, m := range family.GetMetric() {
+		labels := make(map[string]string)
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if !matchLabels(labels, filters) {
+			continue
+		}
+		value, err := prometheusValue(family, m, field)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, metricSeries{labels, value})
+	}
+
+	return series, nil
+}
+
+// Synopsis returns a short synopsis of the `metric` command.
+func (c *MetricCommand) Synopsis() string {
+	return "Check a Vault telemetry metric"
+}
+
+// Help returns a long-form help text of the `metric` command.
+func (c *MetricCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor metric [options]
+
+  This command reads the Vault telemetry metrics exposed by the 'sys/metrics'
+  endpoint and checks the value of the selected metric.
+
+    $ hashicorp-vault-monitor metric -name vault.core.handle_request \
+        -field max -warning 500 -critical 1000
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+    -name=<string>
+       Name of the metric to check (e.g. 'vault.barrier.put'). The dots are
+       converted into underscores when the Prometheus format is selected.
+
+    -label=<string>
+       Comma separated list of label=value filters (e.g. 'mount_point=secret/').
+       The filters must select exactly one series of the metric.
+
+    -field=<string>
+       Field of the metric to check. The gauges only have a 'value'. The
+       counters and samples of the JSON format have the fields 'count',
+       'rate', 'sum', 'min', 'max', 'mean' and 'stddev'. The Prometheus
+       summaries have the fields 'count', 'sum', 'mean' and their quantiles
+       (e.g. '0.99'). The default is 'mean'.
+
+    -metrics-format=<string>
+       Format of the metrics to fetch. Can be 'json' (default) or 'prometheus'.
+       The latter requires the 'prometheus_retention_time' telemetry setting.
+
+%s
+
+    -warning=<string>
+       Warning threshold range in the Nagios format (e.g. '10', '10:', '~:10',
+       '10:20', '@10:20').
+
+    -critical=<string>
+       Critical threshold range in the Nagios format.
+
+  By default, the exit code reflects the metric value:
+
+      - %d - the value is outside the warning and critical ranges
+      - %d - the value is inside the warning range
+      - %d - the value is inside the critical range
+      - %d - an error occurred, or the metric has not been found
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("warning-threshold=critical,error=critical", rangeOutcomes),
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `metric` command with the given CLI instance and command-line arguments.
+func (c *MetricCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("metric", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, rangeOutcomes)
+	cmdFlags.StringVar(&c.Name, "name", "", metricNameDescr)
+	cmdFlags.StringVar(&c.Labels, "label", "", metricLabelDescr)
+	cmdFlags.StringVar(&c.Field, "field", "", metricFieldDescr)
+	cmdFlags.StringVar(&c.MetricsFormat, "metrics-format", "json", metricFormatDescr)
+	cmdFlags.StringVar(&c.Warning, "warning", "", metricWarningDescr)
+	cmdFlags.StringVar(&c.Critical, "critical", "", metricCriticalDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if c.Name == "" {
+		out.Undefined("The name of the metric must be set with '-name'")
+		return StateUndefined
+	}
+
+	filters, err := parseLabelFilters(c.Labels)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	warning, err := parseOptionalRange(c.Warning)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+	critical, err := parseOptionalRange(c.Critical)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	if c.MetricsFormat != "json" && c.MetricsFormat != "prometheus" {
+		out.Undefined("unknown format of the metrics: %s", c.MetricsFormat)
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	var series []metricSeries
+	if c.MetricsFormat == "prometheus" {
+		families, err := readPrometheusMetrics(client)
+		if err != nil {
+			return c.report(out, "error", "error reading the Vault metrics: %s", err)
+		}
+		series, err = selectPrometheusMetric(families, c.Name, c.Field, filters)
+		if err != nil {
+			return c.report(out, "error", "%s", err)
+		}
+	} else {
+		summary, err := readMetrics(client)
+		if err != nil {
+			return c.report(out, "error", "error reading the Vault metrics: %s", err)
+		}
+		series, err = selectJSONMetric(summary, c.Name, c.Field, filters)
+		if err != nil {
+			return c.report(out, "error", "%s", err)
+		}
+	}
+
+	switch len(series) {
+	case 0:
+		return c.report(out, "error", "no metric %s%s found", c.Name, formatLabels(filters))
+	case 1:
+	default:
+		var found []string
+		for _, s := range series {
+			found = append(found, formatLabels(s.labels))
+		}
+		sort.Strings(found)
+		return c.report(out, "error", "%d series of the metric %s match, add a label filter: %s",
+			len(series), c.Name, strings.Join(found, " "))
+	}
+
+	name := c.Name + formatLabels(series[0].labels)
+	if c.Field != "" {
+		name += " " + c.Field
+	}
+
+	return c.report(out, rangeOutcome(series[0].value, warning, critical),
+		"%s is %g", name, series[0].value)
+}
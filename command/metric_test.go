@@ -0,0 +1,276 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+const testMetricsJSON = `{
+  "Timestamp": "2026-10-18 10:00:00 +0000 UTC",
+  "Gauges": [
+    {"Name": "vault.runtime.num_goroutines", "Value": 150, "Labels": {}},
+    {"Name": "vault.secret.kv.count", "Value": 12, "Labels": {"mount_point": "secret/"}},
+    {"Name": "vault.secret.kv.count", "Value": 3, "Labels": {"mount_point": "kv/"}}
+  ],
+  "Counters": [
+    {"Name": "vault.core.handle_login_request", "Count": 4, "Rate": 0.4, "Sum": 4,
+     "Min": 1, "Max": 1, "Mean": 1, "Stddev": 0, "Labels": {}}
+  ],
+  "Samples": [
+    {"Name": "vault.core.handle_request", "Count": 10, "Rate": 2.5, "Sum": 25,
+     "Min": 0.5, "Max": 12.5, "Mean": 2.5, "Stddev": 3.2, "Labels": {}}
+  ]
+}`
+
+const testMetricsPrometheus = `# HELP vault_core_handle_request vault_core_handle_request
+# TYPE vault_core_handle_request summary
+vault_core_handle_request{quantile="0.5"} 1.5
+vault_core_handle_request{quantile="0.9"} 4.5
+vault_core_handle_request{quantile="0.99"} 12
+vault_core_handle_request_sum 25
+vault_core_handle_request_count 10
+# HELP vault_secret_kv_count vault_secret_kv_count
+# TYPE vault_secret_kv_count gauge
+vault_secret_kv_count{mount_point="kv/"} 3
+vault_secret_kv_count{mount_point="secret/"} 12
+`
+
+func testMetricCommand(t *testing.T) (*cli.MockUi, *MetricCommand) {
+	ui := cli.NewMockUi()
+	return ui, &MetricCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestParseLabelFilters(t *testing.T) {
+	filters, err := parseLabelFilters("mount_point=secret/, namespace=root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) != 2 || filters["mount_point"] != "secret/" || filters["namespace"] != "root" {
+		t.Errorf("unexpected label filters: %v", filters)
+	}
+
+	for _, s := range []string{"foo", "=bar", "a=b,foo"} {
+		if _, err := parseLabelFilters(s); err == nil {
+			t.Errorf("expected an error for the label filters '%s'", s)
+		}
+	}
+}
+
+func TestMetricCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		format string
+		args   []string
+		out    string
+		code   int
+	}{
+		{
+			"help_message",
+			"json",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor metric [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			"json",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"missing_name",
+			"json",
+			[]string{},
+			"The name of the metric must be set with '-name'",
+			StateUndefined,
+		},
+		{
+			"bad_threshold",
+			"json",
+			[]string{"-name", "vault.barrier.put", "-warning", "foo"},
+			"invalid threshold range: 'foo'",
+			StateUndefined,
+		},
+		{
+			"bad_format",
+			"json",
+			[]string{"-name", "vault.barrier.put", "-metrics-format", "xml"},
+			"unknown format of the metrics: xml",
+			StateUndefined,
+		},
+		{
+			"not_found",
+			"json",
+			[]string{"-name", "vault.barrier.put"},
+			"no metric vault.barrier.put found",
+			StateUndefined,
+		},
+		{
+			"gauge",
+			"json",
+			[]string{"-name", "vault.runtime.num_goroutines", "-warning", "200"},
+			"vault.runtime.num_goroutines is 150",
+			StateOk,
+		},
+		{
+			"gauge_warning",
+			"json",
+			[]string{"-name", "vault.runtime.num_goroutines", "-warning", "100", "-critical", "500"},
+			"vault.runtime.num_goroutines is 150",
+			StateWarning,
+		},
+		{
+			"ambiguous",
+			"json",
+			[]string{"-name", "vault.secret.kv.count"},
+			"2 series of the metric vault.secret.kv.count match, add a label filter: {mount_point=kv/} {mount_point=secret/}",
+			StateUndefined,
+		},
+		{
+			"label_filter",
+			"json",
+			[]string{"-name", "vault.secret.kv.count", "-label", "mount_point=secret/", "-critical", "10"},
+			"vault.secret.kv.count{mount_point=secret/} is 12",
+			StateCritical,
+		},
+		{
+			"sample_default_field",
+			"json",
+			[]string{"-name", "vault.core.handle_request", "-warning", "2"},
+			"vault.core.handle_request is 2.5",
+			StateWarning,
+		},
+		{
+			"sample_max",
+			"json",
+			[]string{"-name", "vault.core.handle_request", "-field", "max", "-critical", "@10:"},
+			"vault.core.handle_request max is 12.5",
+			StateCritical,
+		},
+		{
+			"counter_count",
+			"json",
+			[]string{"-name", "vault.core.handle_login_request", "-field", "count"},
+			"vault.core.handle_login_request count is 4",
+			StateOk,
+		},
+		{
+			"bad_field",
+			"json",
+			[]string{"-name", "vault.runtime.num_goroutines", "-field", "max"},
+			"unknown field of the gauge vault.runtime.num_goroutines: max",
+			StateUndefined,
+		},
+		{
+			"prometheus_quantile",
+			"prometheus",
+			[]string{"-metrics-format", "prometheus", "-name", "vault.core.handle_request", "-field", "0.99", "-warning", "10"},
+			"vault.core.handle_request 0.99 is 12",
+			StateWarning,
+		},
+		{
+			"prometheus_mean",
+			"prometheus",
+			[]string{"-metrics-format", "prometheus", "-name", "vault_core_handle_request"},
+			"vault_core_handle_request is 2.5",
+			StateOk,
+		},
+		{
+			"prometheus_missing_quantile",
+			"prometheus",
+			[]string{"-metrics-format", "prometheus", "-name", "vault.core.handle_request", "-field", "0.75"},
+			"no quantile 0.75 for the metric vault_core_handle_request",
+			StateUndefined,
+		},
+		{
+			"prometheus_gauge",
+			"prometheus",
+			[]string{"-metrics-format", "prometheus", "-name", "vault.secret.kv.count", "-label", "mount_point=kv/"},
+			"vault.secret.kv.count{mount_point=kv/} is 3",
+			StateOk,
+		},
+		{
+			"nagios_metric",
+			"json",
+			[]string{"-output", "nagios", "-name", "vault.runtime.num_goroutines"},
+			"vault OK - vault.runtime.num_goroutines is 150",
+			StateOk,
+		},
+	}
+
+	t.Run("metric", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				body := testMetricsJSON
+				if tc.format == "prometheus" {
+					body = testMetricsPrometheus
+				}
+				client, closer := testVaultServerJSON(t, map[string]string{
+					"/v1/sys/metrics": body,
+				})
+				defer closer()
+
+				ui, cmd := testMetricCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testMetricCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-name", "vault.barrier.put"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error reading the Vault metrics: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/vault/api"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// metricsGauge is a gauge reported by the `sys/metrics` endpoint.
+type metricsGauge struct {
+	Name   string            `json:"Name"`
+	Value  float64           `json:"Value"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// metricsSample is a counter or a sample (timer) reported by the
+// `sys/metrics` endpoint, aggregated over the current interval.
+type metricsSample struct {
+	Name   string            `json:"Name"`
+	Count  int               `json:"Count"`
+	Rate   float64           `json:"Rate"`
+	Sum    float64           `json:"Sum"`
+	Min    float64           `json:"Min"`
+	Max    float64           `json:"Max"`
+	Mean   float64           `json:"Mean"`
+	Stddev float64           `json:"Stddev"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// metricsSummary is the (JSON) response of the `sys/metrics` endpoint.
+type metricsSummary struct {
+	Timestamp string          `json:"Timestamp"`
+	Gauges    []metricsGauge  `json:"Gauges"`
+	Counters  []metricsSample `json:"Counters"`
+	Samples   []metricsSample `json:"Samples"`
+}
+
+// readMetrics returns the telemetry metrics of the Vault server.
+func readMetrics(client *api.Client) (*metricsSummary, error) {
+	resp, err := client.Logical().ReadRaw("sys/metrics")
+	if err != nil {
+		// the response of a failed request can still hold a body
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var summary metricsSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// readPrometheusMetrics returns the telemetry metrics of the Vault server
+// in the Prometheus format, indexed by the metric family name.
+// The Prometheus retention time must be set in the Vault telemetry settings.
+func readPrometheusMetrics(client *api.Client) (map[string]*dto.MetricFamily, error) {
+	resp, err := client.Logical().ReadRawWithData("sys/metrics",
+		map[string][]string{"format": {"prometheus"}})
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+// gauge returns the sum of the values of all the gauges named name.
+// The boolean is false if no such gauge has been found.
+func (m *metricsSummary) gauge(name string) (float64, bool) {
+	var value float64
+	found := false
+	for _, g := range m.Gauges {
+		if g.Name == name {
+			value += g.Value
+			found = true
+		}
+	}
+	return value, found
+}
@@ -0,0 +1,221 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const stateDescr = "Comma separated list of OUTCOME=STATE overriding the state " +
+	"reported for the given outcomes of the check"
+
+// checkOutcome is a named outcome of a check, reported by default with the
+// given state. The state can be overridden by the -state flag.
+type checkOutcome struct {
+	name  string
+	state int
+	descr string
+}
+
+// errorOutcome is the outcome, shared by all the commands, of a check that
+// cannot be performed.
+var errorOutcome = checkOutcome{"error", StateUndefined, "the check cannot be performed"}
+
+// stateFlagNames maps the state constants to the names used by the -state flag.
+var stateFlagNames = map[int]string{
+	StateOk:        "ok",
+	StateWarning:   "warning",
+	StateCritical:  "critical",
+	StateUndefined: "unknown",
+}
+
+// stateFlag is the value of the -state flag: a comma separated list of
+// OUTCOME=STATE items. The flag can be repeated.
+type stateFlag map[string]int
+
+// String returns the outcomes and their states, sorted by outcome.
+func (s stateFlag) String() string {
+	items := make([]string, 0, len(s))
+	for outcome, state := range s {
+		items = append(items, outcome+"="+stateFlagNames[state])
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// Set parses a comma separated list of OUTCOME=STATE items.
+func (s stateFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		outcome, name, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || outcome == "" {
+			return fmt.Errorf("expected OUTCOME=STATE, got: %s", item)
+		}
+		state, err := ParseState(name)
+		if err != nil {
+			return err
+		}
+		s[outcome] = state
+	}
+	return nil
+}
+
+// addStateFlag adds to the flag set the -state flag, overriding the states
+// of the given outcomes of the check and of the error outcome.
+func (c *BaseCommand) addStateFlag(f *flag.FlagSet, outcomes []checkOutcome) {
+	c.outcomes = append([]checkOutcome{errorOutcome}, outcomes...)
+	c.States = stateFlag{}
+	f.Var(c.States, "state", stateDescr)
+}
+
+// checkStates verifies that the -state flag only overrides the outcomes of
+// the check, and applies the deprecated flags -unknown-as-critical and
+// -sealed-as-warning, unless the same outcomes are explicitly overridden.
+func (c *BaseCommand) checkStates() error {
+	if c.States == nil {
+		return nil
+	}
+
+	var unknown []string
+	for outcome := range c.States {
+		if c.outcome(outcome) == nil {
+			unknown = append(unknown, outcome)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		names := make([]string, len(c.outcomes))
+		for i, o := range c.outcomes {
+			names[i] = o.name
+		}
+		return fmt.Errorf("Unknown outcome: %s (expected one of: %s)",
+			strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+
+	if c.UnknownAsCritical {
+		if err := c.aliasState("error", "critical"); err != nil {
+			return err
+		}
+	}
+	if c.SealedAsWarning {
+		if err := c.aliasState("sealed", "warning"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasState applies a deprecated flag setting the state of the given
+// outcome to name (if not empty), unless the same outcome is explicitly
+// overridden by the -state flag.
+func (c *BaseCommand) aliasState(outcome, name string) error {
+	if _, ok := c.States[outcome]; ok || name == "" {
+		return nil
+	}
+	state, err := ParseState(name)
+	if err != nil {
+		return err
+	}
+	c.States[outcome] = state
+	return nil
+}
+
+// outcome returns the outcome of the check with the given name, or nil if
+// the check has no such outcome.
+func (c *BaseCommand) outcome(name string) *checkOutcome {
+	for i := range c.outcomes {
+		if c.outcomes[i].name == name {
+			return &c.outcomes[i]
+		}
+	}
+	return nil
+}
+
+// state returns the state reported for the given outcome: the one set by the
+// -state flag or, by default, the state declared by the check.
+func (c *BaseCommand) state(outcome string) int {
+	if state, ok := c.States[outcome]; ok {
+		return state
+	}
+	if o := c.outcome(outcome); o != nil {
+		return o.state
+	}
+	if outcome == errorOutcome.name {
+		return errorOutcome.state
+	}
+	return StateUndefined
+}
+
+// report outputs the message of the given outcome with its state, and
+// returns the state.
+func (c *BaseCommand) report(out *Outputter, outcome string, format string, a ...interface{}) int {
+	state := c.state(outcome)
+	out.State(state)(format, a...)
+	return state
+}
+
+// helpTextWidth is the maximum width of the lines of the help texts.
+const helpTextWidth = 78
+
+// helpTextIndent is the indentation of the descriptions of the flags in the
+// help texts.
+const helpTextIndent = "       "
+
+// wrapHelp returns the description text of a flag, wrapped and indented as in
+// the help texts.
+func wrapHelp(text string) string {
+	var lines []string
+	line := helpTextIndent
+	for _, word := range strings.Fields(text) {
+		if line != helpTextIndent && len(line)+1+len(word) > helpTextWidth {
+			lines = append(lines, line)
+			line = helpTextIndent
+		}
+		if line != helpTextIndent {
+			line += " "
+		}
+		line += word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+// stateFlagHelp returns the help text of the -state flag, along with the
+// given example of its value and the outcomes of the check.
+func stateFlagHelp(example string, outcomes []checkOutcome) string {
+	return "    -state=<outcome>=<state>[,...]\n" +
+		wrapHelp("Override the state reported for the given outcomes of the check, "+
+			"for instance -state "+example+". The states are 'ok', 'warning', "+
+			"'critical' and 'unknown'. The outcomes of the check and their "+
+			"default states are:") +
+		"\n\n" + outcomesHelp(outcomes)
+}
+
+// outcomesHelp returns the lines of the help text listing the outcomes of a
+// check, the error outcome included, along with their default states.
+func outcomesHelp(outcomes []checkOutcome) string {
+	outcomes = append([]checkOutcome{errorOutcome}, outcomes...)
+
+	lines := make([]string, 0, 2*len(outcomes))
+	for _, o := range outcomes {
+		lines = append(lines,
+			fmt.Sprintf("         %s=%s", o.name, stateFlagNames[o.state]),
+			"             "+o.descr)
+	}
+	return strings.Join(lines, "\n")
+}
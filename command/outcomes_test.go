@@ -0,0 +1,174 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+var testOutcomes = []checkOutcome{
+	{"unsealed", StateOk, "the Vault server is unsealed"},
+	{"sealed", StateCritical, "the Vault server is sealed"},
+	{"no-leader", StateWarning, "the active node is unknown"},
+}
+
+func TestStateFlag(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		states string
+		err    string
+	}{
+		{"empty", []string{}, "", ""},
+		{"single", []string{"-state", "sealed=warning"}, "sealed=warning", ""},
+		{"list", []string{"-state", "sealed=warning, error=critical"}, "error=critical,sealed=warning", ""},
+		{"repeated", []string{"-state", "sealed=ok", "-state", "sealed=unknown"}, "sealed=unknown", ""},
+		{"missing_state", []string{"-state", "sealed"}, "", "expected OUTCOME=STATE, got: sealed"},
+		{"missing_outcome", []string{"-state", "=ok"}, "", "expected OUTCOME=STATE, got: =ok"},
+		{"unknown_state", []string{"-state", "sealed=fatal"}, "", "unknown state: fatal"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &BaseCommand{}
+			f := flag.NewFlagSet("test", flag.ContinueOnError)
+			f.SetOutput(&strings.Builder{})
+			c.addStateFlag(f, testOutcomes)
+
+			err := f.Parse(tc.args)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if states := c.States.String(); states != tc.states {
+				t.Errorf("expected %q to be %q", states, tc.states)
+			}
+		})
+	}
+}
+
+func TestCheckStates(t *testing.T) {
+	cases := []struct {
+		name              string
+		states            stateFlag
+		unknownAsCritical bool
+		sealedAsWarning   bool
+		expected          map[string]int
+		err               string
+	}{
+		{
+			"defaults",
+			stateFlag{},
+			false, false,
+			map[string]int{"error": StateUndefined, "unsealed": StateOk,
+				"sealed": StateCritical, "no-leader": StateWarning},
+			"",
+		},
+		{
+			"overrides",
+			stateFlag{"sealed": StateWarning, "no-leader": StateCritical},
+			false, false,
+			map[string]int{"error": StateUndefined, "unsealed": StateOk,
+				"sealed": StateWarning, "no-leader": StateCritical},
+			"",
+		},
+		{
+			"deprecated_flags",
+			stateFlag{},
+			true, true,
+			map[string]int{"error": StateCritical, "sealed": StateWarning},
+			"",
+		},
+		{
+			"explicit_states_win",
+			stateFlag{"error": StateOk, "sealed": StateUndefined},
+			true, true,
+			map[string]int{"error": StateOk, "sealed": StateUndefined},
+			"",
+		},
+		{
+			"unknown_outcomes",
+			stateFlag{"standby": StateOk, "active": StateOk},
+			false, false,
+			nil,
+			"Unknown outcome: active, standby (expected one of: error, unsealed, sealed, no-leader)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &BaseCommand{
+				UnknownAsCritical: tc.unknownAsCritical,
+				SealedAsWarning:   tc.sealedAsWarning,
+			}
+			c.addStateFlag(flag.NewFlagSet("test", flag.ContinueOnError), testOutcomes)
+			c.States = tc.states
+
+			err := c.checkStates()
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for outcome, expected := range tc.expected {
+				if state := c.state(outcome); state != expected {
+					t.Errorf("expected the state of %s %d to be %d", outcome, state, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestOutcomesHelp(t *testing.T) {
+	expected := `         error=unknown
+             the check cannot be performed
+         unsealed=ok
+             the Vault server is unsealed
+         sealed=critical
+             the Vault server is sealed
+         no-leader=warning
+             the active node is unknown`
+
+	if help := outcomesHelp(testOutcomes); help != expected {
+		t.Errorf("expected %q to be %q", help, expected)
+	}
+}
+
+func TestStateFlagHelp(t *testing.T) {
+	expected := `    -state=<outcome>=<state>[,...]
+       Override the state reported for the given outcomes of the check, for
+       instance -state sealed=warning,no-leader=critical. The states are 'ok',
+       'warning', 'critical' and 'unknown'. The outcomes of the check and
+       their default states are:
+
+` + outcomesHelp(testOutcomes)
+
+	if help := stateFlagHelp("sealed=warning,no-leader=critical", testOutcomes); help != expected {
+		t.Errorf("expected %q to be %q", help, expected)
+	}
+}
@@ -18,7 +18,13 @@ package command
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 )
 
 // (Nagios compatible) return codes constants.
@@ -29,48 +35,420 @@ const (
 	StateUndefined
 )
 
+// stateNames maps the names accepted on the command-line to the state constants.
+var stateNames = map[string]int{
+	"ok":        StateOk,
+	"warning":   StateWarning,
+	"critical":  StateCritical,
+	"unknown":   StateUndefined,
+	"undefined": StateUndefined,
+}
+
+// ParseState returns the state constant matching the given (case-insensitive) name.
+func ParseState(name string) (int, error) {
+	state, ok := stateNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return StateUndefined, fmt.Errorf("unknown state: %s", name)
+	}
+	return state, nil
+}
+
+// stateLabels maps the state constants to the labels displayed in the
+// monitoring tools output.
+var stateLabels = map[int]string{
+	StateOk:        "OK",
+	StateWarning:   "WARNING",
+	StateCritical:  "CRITICAL",
+	StateUndefined: "UNDEFINED",
+}
+
+// stateSeverity ranks the states by severity, following the usual Nagios
+// convention: critical > warning > unknown > ok.
+var stateSeverity = map[int]int{
+	StateOk:        0,
+	StateUndefined: 1,
+	StateWarning:   2,
+	StateCritical:  3,
+}
+
+// WorstState returns the most severe of the given states.
+func WorstState(states ...int) int {
+	worst := StateOk
+	for _, state := range states {
+		if stateSeverity[state] > stateSeverity[worst] {
+			worst = state
+		}
+	}
+	return worst
+}
+
+// PerfData is a Nagios performance data metric.
+type PerfData struct {
+	Label    string  `json:"label"`
+	Value    float64 `json:"value"`
+	UOM      string  `json:"uom,omitempty"`
+	Warning  string  `json:"warning,omitempty"`
+	Critical string  `json:"critical,omitempty"`
+	Min      string  `json:"min,omitempty"`
+	Max      string  `json:"max,omitempty"`
+}
+
+// String returns the performance data in the Nagios format:
+// 'label'=value[UOM];[warn];[crit];[min];[max]
+func (p PerfData) String() string {
+	label := p.Label
+	if strings.ContainsAny(label, " '=") {
+		label = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	value := strconv.FormatFloat(p.Value, 'f', -1, 64) + p.UOM
+
+	return strings.TrimRight(strings.Join([]string{
+		label + "=" + value, p.Warning, p.Critical, p.Min, p.Max}, ";"), ";")
+}
+
 // Outputter holds the output functions that are monitoring tool dependent.
 type Outputter struct {
 	Output    func(format string, a ...interface{})
 	Warning   func(format string, a ...interface{})
 	Critical  func(format string, a ...interface{})
 	Undefined func(format string, a ...interface{})
+
+	perfdata []PerfData
+	details  []outputDetail
+	template *template.Template
+	data     map[string]interface{}
+}
+
+// outputDetail is a line of the long output, reporting the state of a
+// single item checked by a command.
+type outputDetail struct {
+	state int
+	text  string
+}
+
+// String returns the detail line prefixed by the label of its state.
+func (d outputDetail) String() string {
+	return fmt.Sprintf("[%s] %s", stateLabels[d.state], d.text)
+}
+
+// AddPerfData adds some performance data to the next output message.
+// The performance data is only displayed by the monitoring tools supporting it.
+func (o *Outputter) AddPerfData(p ...PerfData) {
+	o.perfdata = append(o.perfdata, p...)
+}
+
+// AddDetail adds a line of long output, reporting the state of a single item,
+// to the next output message. The details follow the first line of the
+// message, as described by the Nagios plugin development guidelines.
+func (o *Outputter) AddDetail(state int, format string, a ...interface{}) {
+	o.details = append(o.details, outputDetail{state, fmt.Sprintf(format, a...)})
+}
+
+// SetData sets the result data of the command the -format template is
+// evaluated against. The template is only used once the data is set, so the
+// errors occurred before the check is run are reported by the default
+// messages.
+func (o *Outputter) SetData(data map[string]interface{}) {
+	o.data = data
+}
+
+// message returns the output message: the default one, built from format and
+// a, or the one produced by the -format template, if any. Besides the result
+// data of the command, the template can access the default message as
+// .Message and the label of the state as .State.
+func (o *Outputter) message(state int, format string, a ...interface{}) string {
+	message := fmt.Sprintf(format, a...)
+	if o.template == nil || o.data == nil {
+		return message
+	}
+
+	data := map[string]interface{}{
+		"Message": message,
+		"State":   stateLabels[state],
+	}
+	for k, v := range o.data {
+		data[k] = v
+	}
+
+	var buf strings.Builder
+	if err := o.template.Execute(&buf, data); err != nil {
+		return fmt.Sprintf("%s (error in the output template: %s)", message, err)
+	}
+	return buf.String()
+}
+
+// result returns the result of the check reported by the command c with
+// the given state and message.
+func (o *Outputter) result(c *BaseCommand, state int, message string) checkResult {
+	details := make([]string, len(o.details))
+	for i, d := range o.details {
+		details[i] = d.String()
+	}
+	return checkResult{
+		Service:   c.Service(),
+		Command:   c.CommandName,
+		State:     state,
+		StateName: stateLabels[state],
+		Message:   message,
+		Details:   details,
+		PerfData:  o.perfdata,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// formatDetails returns the long output lines, each one preceded by a newline.
+func (o *Outputter) formatDetails() string {
+	var buf strings.Builder
+	for _, d := range o.details {
+		buf.WriteString("\n" + d.String())
+	}
+	return buf.String()
+}
+
+// formatPerfData returns the performance data in the Nagios format,
+// prefixed by a pipe, or an empty string if there is no data.
+func (o *Outputter) formatPerfData() string {
+	if len(o.perfdata) == 0 {
+		return ""
+	}
+	metrics := make([]string, len(o.perfdata))
+	for i, p := range o.perfdata {
+		metrics[i] = p.String()
+	}
+	return " | " + strings.Join(metrics, " ")
+}
+
+// checkmkMetricName matches the characters not allowed in the Checkmk metric names.
+var checkmkMetricName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// formatCheckmkMetrics returns the performance data in the Checkmk local
+// checks format (name=value;warn;crit;min;max|...), or a dash if there is
+// no data. The Checkmk metrics have no unit of measurement and only
+// support plain numbers as thresholds.
+func (o *Outputter) formatCheckmkMetrics() string {
+	if len(o.perfdata) == 0 {
+		return "-"
+	}
+
+	number := func(s string) string {
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return ""
+		}
+		return s
+	}
+
+	metrics := make([]string, len(o.perfdata))
+	for i, p := range o.perfdata {
+		metrics[i] = strings.TrimRight(strings.Join([]string{
+			checkmkMetricName.ReplaceAllString(p.Label, "_") + "=" +
+				strconv.FormatFloat(p.Value, 'f', -1, 64),
+			number(p.Warning), number(p.Critical), number(p.Min), number(p.Max)}, ";"), ";")
+	}
+	return strings.Join(metrics, "|")
+}
+
+// State returns the output function to be used for reporting the given state.
+func (o *Outputter) State(state int) func(format string, a ...interface{}) {
+	switch state {
+	case StateOk:
+		return o.Output
+	case StateWarning:
+		return o.Warning
+	case StateCritical:
+		return o.Critical
+	default:
+		return o.Undefined
+	}
+}
+
+// outputFormatsHelp lists the output formats in the help texts.
+const outputFormatsHelp = "'default', 'nagios', 'checkmk', 'zabbix[:METRIC]', " +
+	"'sensu', 'webhook' or 'prometheus-textfile'"
+
+// outputFlagsHelp returns the help text of the flags selecting the output
+// format and the export of the telemetry data of a check.
+func outputFlagsHelp() string {
+	return "    -output=<string>\n" +
+		wrapHelp("Specify an output format. Can be "+outputFormatsHelp+". "+
+			"The events sent by 'sensu' and 'webhook' are configured by the "+
+			"-event-url, -event-template, -event-retries and -spool-dir flags. "+
+			"The 'prometheus-textfile' format writes the metrics to the file "+
+			"given by the -textfile flag.") +
+		"\n\n    -otlp-endpoint=<string>\n" +
+		wrapHelp("URL of an OpenTelemetry collector (for instance "+
+			"http://127.0.0.1:4317) the result of the check and the traces of "+
+			"the Vault API calls are exported to. The protocol is selected by "+
+			"-otlp-protocol: 'grpc' (the default) or 'http'.")
+}
+
+// batchOutputFlagsHelp returns the help text of the flags selecting the
+// output format and the export of the telemetry data of all the checks run
+// by the `batch` and `agent` commands.
+func batchOutputFlagsHelp() string {
+	return "    -output=<string>\n" +
+		wrapHelp("Specify an output format, used by all the checks. Can be "+
+			outputFormatsHelp+". The checks must not set their own output "+
+			"format. The -event-url, -event-template, -event-retries, "+
+			"-spool-dir and -textfile flags are also passed to all the checks.") +
+		"\n\n    -otlp-endpoint=<string>\n" +
+		wrapHelp("URL of an OpenTelemetry collector the results of the checks "+
+			"and the traces of their Vault API calls are exported to. The "+
+			"protocol is selected by -otlp-protocol: 'grpc' (the default) or "+
+			"'http'.")
+}
+
+// addOutputFlags adds to the flag set the flags selecting and configuring
+// the output format and the export of the telemetry data.
+func (c *BaseCommand) addOutputFlags(f *flag.FlagSet) {
+	f.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	f.StringVar(&c.EventURL, "event-url", "", fmt.Sprintf(eventURLDescr, DefaultSensuURL))
+	f.StringVar(&c.EventTemplate, "event-template", "", eventTemplateDescr)
+	f.IntVar(&c.EventRetries, "event-retries",
+		DefaultEventRetries,
+		fmt.Sprintf(eventRetriesDescr, DefaultEventRetries))
+	f.StringVar(&c.SpoolDir, "spool-dir", "", eventSpoolDirDescr)
+	f.StringVar(&c.Textfile, "textfile", "", textfileDescr)
+	f.StringVar(&c.OTLPEndpoint, "otlp-endpoint", "", otlpEndpointDescr)
+	f.StringVar(&c.OTLPProtocol, "otlp-protocol",
+		DefaultOTLPProtocol,
+		fmt.Sprintf(otlpProtocolDescr, DefaultOTLPProtocol))
+}
+
+// outputArgs returns the command-line arguments selecting the current
+// output format, its options and the export of the telemetry data.
+func (c *BaseCommand) outputArgs() []string {
+	args := []string{"-output", c.OutputFormat}
+	if c.EventURL != "" {
+		args = append(args, "-event-url", c.EventURL)
+	}
+	if c.EventTemplate != "" {
+		args = append(args, "-event-template", c.EventTemplate)
+	}
+	if c.EventRetries != DefaultEventRetries {
+		args = append(args, "-event-retries", strconv.Itoa(c.EventRetries))
+	}
+	if c.SpoolDir != "" {
+		args = append(args, "-spool-dir", c.SpoolDir)
+	}
+	if c.Textfile != "" {
+		args = append(args, "-textfile", c.Textfile)
+	}
+	if c.OTLPEndpoint != "" {
+		args = append(args, "-otlp-endpoint", c.OTLPEndpoint, "-otlp-protocol", c.OTLPProtocol)
+	}
+	return args
 }
 
 // OutputHandle returns the output helper function that is responsible
 // of the command output formatting and return codes selection.
+// It also validates the -state flag. The messages can be customized by a
+// text/template given by the -format flag and, when an OTLP endpoint is set,
+// the result of the check is also exported to the OpenTelemetry collector.
 func (c *BaseCommand) OutputHandle() (*Outputter, error) {
-	switch c.OutputFormat {
+	if err := c.checkStates(); err != nil {
+		return nil, err
+	}
+
+	o, err := c.outputter()
+	if err != nil {
+		return nil, err
+	}
+	if c.MessageTemplate != "" {
+		funcs := template.FuncMap{"join": strings.Join}
+		if o.template, err = template.New("format").Funcs(funcs).Parse(c.MessageTemplate); err != nil {
+			return nil, fmt.Errorf("error parsing the output template: %s", err)
+		}
+	}
+	if c.OTLPEndpoint == "" {
+		return o, nil
+	}
+	return c.exportTelemetry(o)
+}
+
+// outputter returns the Outputter of the selected output format.
+func (c *BaseCommand) outputter() (*Outputter, error) {
+	format, metric, found := strings.Cut(c.OutputFormat, ":")
+	if found && (format != "zabbix" || metric == "") {
+		return nil, errors.New("Unknown outputter: " + c.OutputFormat)
+	}
+
+	switch format {
 	case "default":
-		return &Outputter{
-			Output: func(format string, a ...interface{}) {
-				c.UI.Output(fmt.Sprintf(format, a...))
-			},
-			Warning: func(format string, a ...interface{}) {
-				c.UI.Warn(fmt.Sprintf(format, a...))
-			},
-			Critical: func(format string, a ...interface{}) {
-				c.UI.Error(fmt.Sprintf(format, a...))
-			},
-			Undefined: func(format string, a ...interface{}) {
-				c.UI.Error(fmt.Sprintf(format, a...))
-			},
-		}, nil
+		o := &Outputter{}
+		display := func(state int, print func(string)) func(format string, a ...interface{}) {
+			return func(format string, a ...interface{}) {
+				print(o.message(state, format, a...) + o.formatDetails())
+			}
+		}
+		o.Output = display(StateOk, c.UI.Output)
+		o.Warning = display(StateWarning, c.UI.Warn)
+		o.Critical = display(StateCritical, c.UI.Error)
+		o.Undefined = display(StateUndefined, c.UI.Error)
+		return o, nil
 	case "nagios":
-		return &Outputter{
-			Output: func(format string, a ...interface{}) {
-				c.UI.Info(fmt.Sprintf("vault OK - "+format, a...))
-			},
-			Warning: func(format string, a ...interface{}) {
-				c.UI.Info(fmt.Sprintf("vault WARNING - "+format, a...))
-			},
-			Critical: func(format string, a ...interface{}) {
-				c.UI.Info(fmt.Sprintf("vault CRITICAL - "+format, a...))
-			},
-			Undefined: func(format string, a ...interface{}) {
-				c.UI.Info(fmt.Sprintf("vault UNDEFINED - "+format, a...))
-			},
-		}, nil
+		// The first line holds the summary and the performance data,
+		// the following ones the long output.
+		o := &Outputter{}
+		nagios := func(state int) func(format string, a ...interface{}) {
+			return func(format string, a ...interface{}) {
+				summary, long, multiline := strings.Cut(o.message(state, format, a...), "\n")
+				message := "vault " + stateLabels[state] + " - " + summary + o.formatPerfData()
+				if multiline {
+					message += "\n" + long
+				}
+				c.UI.Info(message + o.formatDetails())
+			}
+		}
+		o.Output = nagios(StateOk)
+		o.Warning = nagios(StateWarning)
+		o.Critical = nagios(StateCritical)
+		o.Undefined = nagios(StateUndefined)
+		return o, nil
+	case "checkmk":
+		// Checkmk local check: <state> "<service>" <metrics> <text>,
+		// where the '\n' sequences separate the summary and the details.
+		o := &Outputter{}
+		checkmk := func(state int) func(format string, a ...interface{}) {
+			return func(format string, a ...interface{}) {
+				text := o.message(state, format, a...) + o.formatDetails()
+				c.UI.Info(fmt.Sprintf("%d \"%s\" %s %s", state, c.Service(),
+					o.formatCheckmkMetrics(), strings.ReplaceAll(text, "\n", "\\n")))
+			}
+		}
+		o.Output = checkmk(StateOk)
+		o.Warning = checkmk(StateWarning)
+		o.Critical = checkmk(StateCritical)
+		o.Undefined = checkmk(StateUndefined)
+		return o, nil
+	case "sensu", "webhook":
+		return c.eventOutputter(format)
+	case "prometheus-textfile":
+		return c.prometheusOutputter()
+	case "zabbix":
+		// Zabbix UserParameter: a single value, the state of the check or
+		// the value of the selected performance data metric.
+		o := &Outputter{}
+		zabbix := func(state int) func(format string, a ...interface{}) {
+			return func(format string, a ...interface{}) {
+				if metric == "" {
+					c.UI.Info(strconv.Itoa(state))
+					return
+				}
+				for _, p := range o.perfdata {
+					if p.Label == metric {
+						c.UI.Info(strconv.FormatFloat(p.Value, 'f', -1, 64))
+						return
+					}
+				}
+				// the item becomes not supported
+				c.UI.Error(fmt.Sprintf("no such metric: %s (%s)", metric, o.message(state, format, a...)))
+			}
+		}
+		o.Output = zabbix(StateOk)
+		o.Warning = zabbix(StateWarning)
+		o.Critical = zabbix(StateCritical)
+		o.Undefined = zabbix(StateUndefined)
+		return o, nil
 	default:
 		return nil, errors.New("Unknown outputter: " + c.OutputFormat)
 	}
@@ -0,0 +1,272 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestParseState(t *testing.T) {
+	cases := []struct {
+		name     string
+		shouldbe int
+		fail     bool
+	}{
+		{"ok", StateOk, false},
+		{"WARNING", StateWarning, false},
+		{" critical ", StateCritical, false},
+		{"unknown", StateUndefined, false},
+		{"undefined", StateUndefined, false},
+		{"nosuchstate", StateUndefined, true},
+	}
+
+	for _, tc := range cases {
+		state, err := ParseState(tc.name)
+		if (err != nil) != tc.fail {
+			t.Errorf("For %q unexpected error value: %v", tc.name, err)
+		}
+		if state != tc.shouldbe {
+			t.Errorf("For %q expected %d got %d", tc.name, tc.shouldbe, state)
+		}
+	}
+}
+
+func TestWorstState(t *testing.T) {
+	cases := []struct {
+		states   []int
+		shouldbe int
+	}{
+		{[]int{}, StateOk},
+		{[]int{StateOk, StateOk}, StateOk},
+		{[]int{StateOk, StateUndefined}, StateUndefined},
+		{[]int{StateUndefined, StateWarning}, StateWarning},
+		{[]int{StateCritical, StateUndefined}, StateCritical},
+		{[]int{StateWarning, StateCritical, StateOk}, StateCritical},
+	}
+
+	for _, tc := range cases {
+		if v := WorstState(tc.states...); v != tc.shouldbe {
+			t.Error("For", tc.states, "expected", tc.shouldbe, "got", v)
+		}
+	}
+}
+
+func TestPerfData(t *testing.T) {
+	cases := []struct {
+		perfdata PerfData
+		shouldbe string
+	}{
+		{PerfData{Label: "time", Value: 12.5, UOM: "ms"}, "time=12.5ms"},
+		{PerfData{Label: "time", Value: 3, UOM: "ms", Warning: "200", Min: "0"}, "time=3ms;200;;0"},
+		{PerfData{Label: "leases", Value: 10, Warning: "100", Critical: "200", Min: "0", Max: "1000"},
+			"leases=10;100;200;0;1000"},
+		{PerfData{Label: "auth token", Value: 1}, "'auth token'=1"},
+	}
+
+	for _, tc := range cases {
+		if v := tc.perfdata.String(); v != tc.shouldbe {
+			t.Errorf("For %+v expected %q got %q", tc.perfdata, tc.shouldbe, v)
+		}
+	}
+}
+
+func TestOutputHandle_LongOutput(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		message  string
+		shouldbe string
+	}{
+		{
+			"default",
+			"default",
+			"2 policies checked",
+			"2 policies checked\n[OK] policy app is defined\n[CRITICAL] no such Vault policy: ops\n",
+		},
+		{
+			"nagios",
+			"nagios",
+			"2 policies checked",
+			"vault CRITICAL - 2 policies checked | policies=2;;;0\n" +
+				"[OK] policy app is defined\n[CRITICAL] no such Vault policy: ops\n",
+		},
+		{
+			"nagios_multiline",
+			"nagios",
+			"2 policies checked\napp, ops",
+			"vault CRITICAL - 2 policies checked | policies=2;;;0\napp, ops\n" +
+				"[OK] policy app is defined\n[CRITICAL] no such Vault policy: ops\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &BaseCommand{UI: ui, OutputFormat: tc.format}
+
+			out, err := c.OutputHandle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.AddPerfData(PerfData{Label: "policies", Value: 2, Min: "0"})
+			out.AddDetail(StateOk, "policy %s is defined", "app")
+			out.AddDetail(StateCritical, "no such Vault policy: %s", "ops")
+			out.Critical(tc.message)
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if combined != tc.shouldbe {
+				t.Errorf("expected %q to be %q", combined, tc.shouldbe)
+			}
+		})
+	}
+}
+
+func TestOutputHandle_Checkmk(t *testing.T) {
+	ui := cli.NewMockUi()
+	c := &BaseCommand{UI: ui, CommandName: "probe", OutputFormat: "checkmk"}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.AddPerfData(
+		PerfData{Label: "time avg", Value: 12.5, UOM: "ms", Warning: "~:200", Critical: "500", Min: "0"},
+		PerfData{Label: "requests", Value: 5})
+	out.AddDetail(StateWarning, "slow request")
+	out.Warning("latency is %gms", 12.5)
+
+	expected := "1 \"Vault probe\" time_avg=12.5;;500;0|requests=5 latency is 12.5ms\\n[WARNING] slow request\n"
+	if combined := ui.OutputWriter.String() + ui.ErrorWriter.String(); combined != expected {
+		t.Errorf("expected %q to be %q", combined, expected)
+	}
+}
+
+func TestOutputHandle_Zabbix(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		shouldbe string
+		fail     bool
+	}{
+		{"state", "zabbix", "1\n", false},
+		{"metric", "zabbix:ttl", "3600\n", false},
+		{"no_such_metric", "zabbix:leases", "no such metric: leases (the token will expire soon)\n", false},
+		{"empty_metric", "zabbix:", "", true},
+		{"not_zabbix", "nagios:ttl", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &BaseCommand{UI: ui, OutputFormat: tc.format}
+
+			out, err := c.OutputHandle()
+			if (err != nil) != tc.fail {
+				t.Fatalf("unexpected error value: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			out.AddPerfData(PerfData{Label: "ttl", Value: 3600, UOM: "s"})
+			out.Warning("the token will expire soon")
+
+			if combined := ui.OutputWriter.String() + ui.ErrorWriter.String(); combined != tc.shouldbe {
+				t.Errorf("expected %q to be %q", combined, tc.shouldbe)
+			}
+		})
+	}
+}
+
+func TestOutputHandle_Template(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		template string
+		data     map[string]interface{}
+		shouldbe string
+		fail     bool
+	}{
+		{
+			"default",
+			"default",
+			`{{.State}}: {{.ClusterName}} is sealed, see https://wiki.example.com/{{.ClusterName}}`,
+			map[string]interface{}{"ClusterName": "vault-prod"},
+			"WARNING: vault-prod is sealed, see https://wiki.example.com/vault-prod\n",
+			false,
+		},
+		{
+			"nagios",
+			"nagios",
+			`{{.Message}} ({{join .Policies ","}})`,
+			map[string]interface{}{"Policies": []string{"default", "admin"}},
+			"vault WARNING - Vault is sealed! (default,admin)\n",
+			false,
+		},
+		{
+			"no_data",
+			"default",
+			`{{.ClusterName}} is sealed`,
+			nil,
+			"Vault is sealed!\n",
+			false,
+		},
+		{
+			"execution_error",
+			"default",
+			`{{index .Policies 5}}`,
+			map[string]interface{}{"Policies": []string{"default"}},
+			"Vault is sealed! (error in the output template: ",
+			false,
+		},
+		{
+			"parse_error",
+			"default",
+			`{{.ClusterName`,
+			nil,
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := &BaseCommand{UI: ui, OutputFormat: tc.format, MessageTemplate: tc.template}
+
+			out, err := c.OutputHandle()
+			if (err != nil) != tc.fail {
+				t.Fatalf("unexpected error value: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			if tc.data != nil {
+				out.SetData(tc.data)
+			}
+			out.Warning("Vault is sealed!")
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.HasPrefix(combined, tc.shouldbe) {
+				t.Errorf("expected %q to start with %q", combined, tc.shouldbe)
+			}
+		})
+	}
+}
@@ -19,12 +19,37 @@ package command
 import (
 	"flag"
 	"fmt"
+	"path"
+	"regexp"
+	"strings"
 )
 
+const (
+	policiesMatchDefault = "exact"
+	policiesMatchDescr   = "Policy names matching mode ('exact', 'glob' or 'regex')"
+
+	policiesCheckExtraDescr = "Also report the active policies not matching any of the given ones"
+	policiesExtraStateDescr = "Deprecated, same as -state extra=STATE"
+)
+
+// builtinPolicies lists the policies that Vault always defines and that are
+// never reported as unexpected.
+var builtinPolicies = []string{"default", "root"}
+
+// policiesOutcomes are the outcomes of the `policies` command.
+var policiesOutcomes = []checkOutcome{
+	{"defined", StateOk, "the given policies are defined"},
+	{"missing", StateCritical, "a given policy is not defined"},
+	{"extra", StateWarning, "an unexpected policy is defined (with -check-extra)"},
+}
+
 // PoliciesCommand is a CLI Command that holds the attributes of the command `policies`.
 type PoliciesCommand struct {
 	*BaseCommand
-	Policies []string
+	Policies   []string
+	Match      string
+	CheckExtra bool
+	ExtraState string
 }
 
 func contains(items []string, item string) bool {
@@ -36,6 +61,69 @@ func contains(items []string, item string) bool {
 	return false
 }
 
+// policyMatcher returns a function reporting whether a policy name matches
+// the given pattern, according to the selected matching mode.
+func policyMatcher(mode, pattern string) (func(string) bool, error) {
+	switch mode {
+	case "exact":
+		return func(name string) bool { return name == pattern }, nil
+	case "glob":
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern '%s': %s", pattern, err)
+		}
+		return func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}, nil
+	case "regex":
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression '%s': %s", pattern, err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, fmt.Errorf("unknown matching mode: %s", mode)
+	}
+}
+
+// checkPolicies compares the active policies against the expected patterns
+// and returns the patterns not matching any active policy and the active
+// policies (built-in ones excluded) not matched by any pattern.
+func checkPolicies(active, patterns []string, mode string) ([]string, []string, error) {
+	matchers := make([]func(string) bool, len(patterns))
+	for i, pattern := range patterns {
+		m, err := policyMatcher(mode, pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		matchers[i] = m
+	}
+
+	var missing, extra []string
+	matched := make(map[string]bool)
+
+	for i, m := range matchers {
+		found := false
+		for _, policy := range active {
+			if m(policy) {
+				matched[policy] = true
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, patterns[i])
+		}
+	}
+
+	for _, policy := range active {
+		if !matched[policy] && !contains(builtinPolicies, policy) {
+			extra = append(extra, policy)
+		}
+	}
+
+	return missing, extra, nil
+}
+
 // Synopsis returns a short synopsis of the `policies` command.
 func (c *PoliciesCommand) Synopsis() string {
 	return "Check the active policies of a Vault server"
@@ -50,6 +138,8 @@ Usage: hashicorp-vault-monitor policies [options] POLICIES
 
     $ hashicorp-vault-monitor policies custpolicy1 custpolicy1 ...
 
+  All the given policies are checked and every missing one is reported.
+
   Additional flags and more advanced use cases are detailed below.
 
     -address=<string>
@@ -60,19 +150,35 @@ Usage: hashicorp-vault-monitor policies [options] POLICIES
        Specify a token for authentication. This can also be specified via the
        VAULT_TOKEN environment variable.
 
-    -output=<string>
-       Specify an output format. Can be 'default' or 'nagios'.
+%s
+
+%s
+
+    -match=<string>
+       How the given policies are compared with the active ones. Can be
+       'exact' (default), 'glob' (shell patterns, e.g. 'app-*') or 'regex'
+       (regular expressions matching the whole policy name).
+
+    -check-extra
+       Also report the active policies that do not match any of the given
+       ones. The built-in policies 'default' and 'root' are never reported.
+
+    -extra-state=<string>
+       Deprecated, same as -state extra=<string>.
 
-  The exit code reflects the status of the policies:
+  By default, the exit code reflects the status of the policies:
 
       - %d - the given policies are configured
+      - %d - unexpected policies were found (with -check-extra)
       - %d - at least one policy was not found
       - %d - an error occurred
 
   For a full list of examples, please see the online documentation.
 `
 	return fmt.Sprintf(helpText,
-		StateOk, StateCritical, StateUndefined)
+		outputFlagsHelp(),
+		stateFlagHelp("missing=warning,extra=critical", policiesOutcomes),
+		StateOk, StateWarning, StateCritical, StateUndefined)
 }
 
 // Run executes the `policies` command with the given CLI instance and command-line arguments.
@@ -81,7 +187,11 @@ func (c *PoliciesCommand) Run(args []string) int {
 	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
 	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
 	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
-	cmdFlags.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.Match, "match", policiesMatchDefault, policiesMatchDescr)
+	cmdFlags.BoolVar(&c.CheckExtra, "check-extra", false, policiesCheckExtraDescr)
+	c.addStateFlag(cmdFlags, policiesOutcomes)
+	cmdFlags.StringVar(&c.ExtraState, "extra-state", "", policiesExtraStateDescr)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(err.Error())
@@ -102,6 +212,11 @@ func (c *PoliciesCommand) Run(args []string) int {
 
 	c.Policies = args[0:]
 
+	if err := c.aliasState("extra", c.ExtraState); err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		out.Undefined(err.Error())
@@ -110,17 +225,46 @@ func (c *PoliciesCommand) Run(args []string) int {
 
 	activePolicies, err := client.Sys().ListPolicies()
 	if err != nil {
-		out.Undefined("error checking policies: %s", err)
-		return StateUndefined
+		return c.report(out, "error", "error checking policies: %s", err)
+	}
+
+	missing, extra, err := checkPolicies(activePolicies, c.Policies, c.Match)
+	if err != nil {
+		return c.report(out, "error", "%s", err)
+	}
+	if !c.CheckExtra {
+		extra = nil
 	}
 
 	for _, policy := range c.Policies {
-		if !contains(activePolicies, policy) {
-			out.Critical("no such Vault policy: %s", policy)
-			return StateCritical
+		if contains(missing, policy) {
+			out.AddDetail(c.state("missing"), "no such Vault policy: %s", policy)
+		} else {
+			out.AddDetail(c.state("defined"), "policy %s is defined", policy)
 		}
 	}
+	for _, policy := range extra {
+		out.AddDetail(c.state("extra"), "unexpected Vault policy: %s", policy)
+	}
+
+	var messages []string
+	retCode := c.state("defined")
+
+	if len(missing) > 0 {
+		messages = append(messages,
+			fmt.Sprintf("no such Vault policy: %s", strings.Join(missing, ", ")))
+		retCode = WorstState(retCode, c.state("missing"))
+	}
+	if len(extra) > 0 {
+		messages = append(messages,
+			fmt.Sprintf("unexpected Vault policy: %s", strings.Join(extra, ", ")))
+		retCode = WorstState(retCode, c.state("extra"))
+	}
+
+	if len(messages) == 0 {
+		return c.report(out, "defined", "all the policies are defined")
+	}
 
-	out.Output("all the policies are defined")
-	return StateOk
+	out.State(retCode)("%s", strings.Join(messages, "; "))
+	return retCode
 }
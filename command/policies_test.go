@@ -20,6 +20,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-test/deep"
 	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 )
@@ -67,6 +68,84 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestCheckPolicies(t *testing.T) {
+	active := []string{"app-frontend", "app-backend", "db-admin", "default", "root"}
+
+	cases := []struct {
+		name     string
+		patterns []string
+		mode     string
+		missing  []string
+		extra    []string
+	}{
+		{
+			"exact_all_found",
+			[]string{"app-frontend", "app-backend", "db-admin"},
+			"exact",
+			nil,
+			nil,
+		},
+		{
+			"exact_all_missing_reported",
+			[]string{"app-frontend", "nosuchpolicy1", "nosuchpolicy2"},
+			"exact",
+			[]string{"nosuchpolicy1", "nosuchpolicy2"},
+			[]string{"app-backend", "db-admin"},
+		},
+		{
+			"glob",
+			[]string{"app-*"},
+			"glob",
+			nil,
+			[]string{"db-admin"},
+		},
+		{
+			"glob_missing",
+			[]string{"app-*", "kv-*"},
+			"glob",
+			[]string{"kv-*"},
+			[]string{"db-admin"},
+		},
+		{
+			"regex",
+			[]string{"app-(frontend|backend)", "db-.*"},
+			"regex",
+			nil,
+			nil,
+		},
+		{
+			"regex_is_anchored",
+			[]string{"app", "db-.*"},
+			"regex",
+			[]string{"app"},
+			[]string{"app-frontend", "app-backend"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			missing, extra, err := checkPolicies(active, tc.patterns, tc.mode)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := deep.Equal(missing, tc.missing); diff != nil {
+				t.Errorf("missing policies: %v", diff)
+			}
+			if diff := deep.Equal(extra, tc.extra); diff != nil {
+				t.Errorf("extra policies: %v", diff)
+			}
+		})
+	}
+
+	t.Run("invalid_patterns", func(t *testing.T) {
+		for _, mode := range []string{"glob", "regex", "nosuchmode"} {
+			if _, _, err := checkPolicies(active, []string{"[app"}, mode); err == nil {
+				t.Errorf("expected an error for mode %s", mode)
+			}
+		}
+	})
+}
+
 func testPoliciesCommand(t *testing.T, token string) (*cli.MockUi, *PoliciesCommand) {
 	ui := cli.NewMockUi()
 	return ui, &PoliciesCommand{
@@ -122,6 +201,54 @@ func TestPoliciesCommand_Run(t *testing.T) {
 			"no such Vault policy: nosuchpolicy",
 			StateCritical,
 		},
+		{
+			"all_missing_policies",
+			[]string{"default", "nosuchpolicy1", "nosuchpolicy2"},
+			"no such Vault policy: nosuchpolicy1, nosuchpolicy2",
+			StateCritical,
+		},
+		{
+			"glob_policies",
+			[]string{"-match", "glob", "def*", "ro?t"},
+			"all the policies are defined",
+			StateOk,
+		},
+		{
+			"regex_policies",
+			[]string{"-match", "regex", "default|root"},
+			"all the policies are defined",
+			StateOk,
+		},
+		{
+			"invalid_match_mode",
+			[]string{"-match", "nosuchmode", "default"},
+			"unknown matching mode: nosuchmode",
+			StateUndefined,
+		},
+		{
+			"extra_policies",
+			[]string{"-check-extra", "default"},
+			"unexpected Vault policy: policy",
+			StateWarning,
+		},
+		{
+			"extra_policies_critical",
+			[]string{"-check-extra", "-extra-state", "critical", "default"},
+			"unexpected Vault policy: policy",
+			StateCritical,
+		},
+		{
+			"extra_policies_ok",
+			[]string{"-check-extra", "-extra-state", "ok", "default"},
+			"unexpected Vault policy: policy",
+			StateOk,
+		},
+		{
+			"invalid_extra_state",
+			[]string{"-check-extra", "-extra-state", "nosuchstate", "default"},
+			"unknown state: nosuchstate",
+			StateUndefined,
+		},
 		{
 			"nagios_not_enough_args",
 			[]string{"-output", "nagios"},
@@ -162,6 +289,10 @@ func TestPoliciesCommand_Run(t *testing.T) {
 				client, _, closer := testVaultServerUnseal(t)
 				defer closer()
 
+				if err := client.Sys().PutPolicy("policy", `path "secret/*" { capabilities = ["read"] }`); err != nil {
+					t.Fatal(err)
+				}
+
 				secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
 					Policies: []string{"policy"},
 					TTL:      "30m",
@@ -0,0 +1,433 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Default values for the `probe` command.
+const (
+	DefaultProbeCount     = 5
+	DefaultProbeInterval  = "100ms"
+	DefaultProbeOperation = "seal-status"
+	DefaultProbeStat      = "p95"
+)
+
+const (
+	probeOperationDescr    = "Operation to probe: seal-status, token-lookup, kv-read or transit (default: %s)"
+	probePathDescr         = "Path of the secret to read for the kv-read operation"
+	probeTransitMountDescr = "Mount path of the transit secrets engine (default: %s)"
+	probeTransitKeyDescr   = "Name of the transit key for the transit operation"
+	probeCountDescr        = "Number of times the operation is performed (default: %d)"
+	probeIntervalDescr     = "Time to wait between two operations (default: %s)"
+	probeStatDescr         = "Latency statistic checked against the thresholds: min, avg, p95 or max (default: %s)"
+	probeWarningDescr      = "Warning threshold range of the latency, in milliseconds (Nagios format)"
+	probeCriticalDescr     = "Critical threshold range of the latency, in milliseconds (Nagios format)"
+)
+
+// probeStats are the latency statistics that can be checked.
+var probeStats = []string{"min", "avg", "p95", "max"}
+
+// ProbeCommand is a CLI Command that holds the attributes of the command `probe`.
+type ProbeCommand struct {
+	*BaseCommand
+	Operation    string
+	Path         string
+	TransitMount string
+	TransitKey   string
+	Count        int
+	Interval     string
+	Stat         string
+	Warning      string
+	Critical     string
+}
+
+// probeTiming holds the timings of a single probe.
+type probeTiming struct {
+	total      time.Duration
+	connect    time.Duration
+	processing time.Duration
+}
+
+// probeTracer collects the connect and processing times of the HTTP
+// requests performed with its context.
+type probeTracer struct {
+	mu            sync.Mutex
+	timing        probeTiming
+	connectStart  time.Time
+	dnsStart      time.Time
+	tlsStart      time.Time
+	wroteRequest  time.Time
+	newConnection bool
+}
+
+// context returns a context tracing the HTTP requests.
+func (pt *probeTracer) context(ctx context.Context) context.Context {
+	elapsed := func(start *time.Time, d *time.Duration) {
+		pt.mu.Lock()
+		defer pt.mu.Unlock()
+		if !start.IsZero() {
+			*d += time.Since(*start)
+			*start = time.Time{}
+		}
+	}
+	begin := func(start *time.Time) {
+		pt.mu.Lock()
+		defer pt.mu.Unlock()
+		*start = time.Now()
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { begin(&pt.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { elapsed(&pt.dnsStart, &pt.timing.connect) },
+		ConnectStart: func(string, string) {
+			begin(&pt.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			elapsed(&pt.connectStart, &pt.timing.connect)
+		},
+		TLSHandshakeStart: func() { begin(&pt.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			elapsed(&pt.tlsStart, &pt.timing.connect)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			pt.mu.Lock()
+			defer pt.mu.Unlock()
+			if !info.Reused {
+				pt.newConnection = true
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { begin(&pt.wroteRequest) },
+		GotFirstResponseByte: func() {
+			elapsed(&pt.wroteRequest, &pt.timing.processing)
+		},
+	})
+}
+
+// probeOperation returns the function performing the given operation.
+func (c *ProbeCommand) probeOperation(client *api.Client) (func(ctx context.Context) error, error) {
+	switch c.Operation {
+	case "seal-status":
+		return func(ctx context.Context) error {
+			_, err := client.Sys().SealStatusWithContext(ctx)
+			return err
+		}, nil
+	case "token-lookup":
+		return func(ctx context.Context) error {
+			_, err := client.Auth().Token().LookupSelfWithContext(ctx)
+			return err
+		}, nil
+	case "kv-read":
+		if c.Path == "" {
+			return nil, errors.New("The path of the secret must be set with '-path'")
+		}
+		return func(ctx context.Context) error {
+			secret, err := client.Logical().ReadWithContext(ctx, c.Path)
+			if err != nil {
+				return err
+			}
+			if secret == nil {
+				return fmt.Errorf("no secret found at %s", c.Path)
+			}
+			return nil
+		}, nil
+	case "transit":
+		if c.TransitKey == "" {
+			return nil, errors.New("The transit key must be set with '-transit-key'")
+		}
+		return func(ctx context.Context) error {
+			return transitRoundtrip(ctx, client, c.TransitMount, c.TransitKey,
+				[]byte("hashicorp-vault-monitor probe"))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operation: %s", c.Operation)
+}
+
+// latencyStats returns the min, avg, p95 and max of the given durations,
+// in milliseconds. The p95 is computed using the nearest-rank method.
+func latencyStats(durations []time.Duration) map[string]float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+
+	return map[string]float64{
+		"min": milliseconds(sorted[0]),
+		"avg": milliseconds(sum / time.Duration(len(sorted))),
+		"p95": milliseconds(sorted[rank]),
+		"max": milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// milliseconds returns d in milliseconds, rounded to the microsecond.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+// Synopsis returns a short synopsis of the `probe` command.
+func (c *ProbeCommand) Synopsis() string {
+	return "Measure the latency of a Vault operation"
+}
+
+// Help returns a long-form help text of the `probe` command.
+func (c *ProbeCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor probe [options]
+
+  This command performs a Vault operation several times and reports the
+  minimum, average, 95th percentile and maximum latency, along with the
+  average time spent for connecting to the server and for processing the
+  requests. The latencies are also reported as performance data.
+
+    $ hashicorp-vault-monitor probe -operation kv-read -path secret/probe \
+        -warning 200 -critical 500
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -operation=<string>
+       Operation to probe (default: %s). Can be 'seal-status', 'token-lookup'
+       (lookup of the token in use), 'kv-read' (read of the secret at -path)
+       or 'transit' (encryption and decryption roundtrip with -transit-key).
+
+    -path=<string>
+       Path of the secret read by the 'kv-read' operation (for instance
+       'secret/probe', or 'secret/data/probe' for a KV version 2 store).
+
+    -transit-mount=<string>
+       Mount path of the transit secrets engine (default: %s).
+
+    -transit-key=<string>
+       Name of the transit key used by the 'transit' operation.
+
+    -count=<int>
+       Number of times the operation is performed (default: %d).
+
+    -interval=<duration>
+       Time to wait between two operations (default: %s).
+
+    -stat=<string>
+       Latency statistic checked against the thresholds. Can be 'min', 'avg',
+       'p95' or 'max' (default: %s).
+
+    -warning=<string>
+       Warning threshold range of the latency, in milliseconds, in the Nagios
+       format (e.g. '200').
+
+    -critical=<string>
+       Critical threshold range of the latency, in milliseconds, in the Nagios
+       format.
+
+  The connect time is the time spent for the DNS resolution, the TCP
+  connection and the TLS handshake of the new connections. The processing
+  time is the time elapsed between the end of the request and the first byte
+  of the response.
+
+  By default, the exit code reflects the measured latency:
+
+      - %d - the latency is outside the warning and critical ranges
+      - %d - the latency is inside the warning range
+      - %d - the latency is inside the critical range
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("warning-threshold=critical,error=critical", rangeOutcomes),
+		DefaultProbeOperation,
+		DefaultTransitMount,
+		DefaultProbeCount,
+		DefaultProbeInterval,
+		DefaultProbeStat,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `probe` command with the given CLI instance and command-line arguments.
+func (c *ProbeCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("probe", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, rangeOutcomes)
+	cmdFlags.StringVar(&c.Operation, "operation",
+		DefaultProbeOperation,
+		fmt.Sprintf(probeOperationDescr, DefaultProbeOperation))
+	cmdFlags.StringVar(&c.Path, "path", "", probePathDescr)
+	cmdFlags.StringVar(&c.TransitMount, "transit-mount",
+		DefaultTransitMount,
+		fmt.Sprintf(probeTransitMountDescr, DefaultTransitMount))
+	cmdFlags.StringVar(&c.TransitKey, "transit-key", "", probeTransitKeyDescr)
+	cmdFlags.IntVar(&c.Count, "count",
+		DefaultProbeCount,
+		fmt.Sprintf(probeCountDescr, DefaultProbeCount))
+	cmdFlags.StringVar(&c.Interval, "interval",
+		DefaultProbeInterval,
+		fmt.Sprintf(probeIntervalDescr, DefaultProbeInterval))
+	cmdFlags.StringVar(&c.Stat, "stat",
+		DefaultProbeStat,
+		fmt.Sprintf(probeStatDescr, DefaultProbeStat))
+	cmdFlags.StringVar(&c.Warning, "warning", "", probeWarningDescr)
+	cmdFlags.StringVar(&c.Critical, "critical", "", probeCriticalDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if c.Count < 1 {
+		out.Undefined("The number of operations must be at least 1")
+		return StateUndefined
+	}
+	if !contains(probeStats, c.Stat) {
+		out.Undefined("unknown latency statistic: %s", c.Stat)
+		return StateUndefined
+	}
+
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	warning, err := parseOptionalRange(c.Warning)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+	critical, err := parseOptionalRange(c.Critical)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	operation, err := c.probeOperation(client)
+	if err != nil {
+		return c.report(out, "error", "%s", err)
+	}
+
+	var latencies, processing []time.Duration
+	var connect time.Duration
+	connections := 0
+
+	for i := 0; i < c.Count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		tracer := &probeTracer{}
+		start := time.Now()
+		err := operation(tracer.context(context.Background()))
+		elapsed := time.Since(start)
+		if err != nil {
+			return c.report(out, "error", "error probing %s: %s", c.Operation, err)
+		}
+
+		tracer.mu.Lock()
+		latencies = append(latencies, elapsed)
+		processing = append(processing, tracer.timing.processing)
+		if tracer.newConnection {
+			connect += tracer.timing.connect
+			connections++
+		}
+		tracer.mu.Unlock()
+	}
+
+	stats := latencyStats(latencies)
+	processingAvg := latencyStats(processing)["avg"]
+	var connectAvg float64
+	if connections > 0 {
+		connectAvg = milliseconds(connect / time.Duration(connections))
+	}
+
+	for _, stat := range probeStats {
+		p := PerfData{Label: "time_" + stat, Value: stats[stat], UOM: "ms", Min: "0"}
+		if stat == c.Stat {
+			if warning != nil {
+				p.Warning = warning.String()
+			}
+			if critical != nil {
+				p.Critical = critical.String()
+			}
+		}
+		out.AddPerfData(p)
+	}
+	out.AddPerfData(
+		PerfData{Label: "connect_avg", Value: connectAvg, UOM: "ms", Min: "0"},
+		PerfData{Label: "processing_avg", Value: processingAvg, UOM: "ms", Min: "0"})
+
+	var summary []string
+	for _, stat := range probeStats {
+		summary = append(summary, fmt.Sprintf("%s %gms", stat, stats[stat]))
+	}
+
+	return c.report(out, rangeOutcome(stats[c.Stat], warning, critical),
+		"%s latency over %d requests: %s (connect %gms, processing %gms)",
+		c.Operation, c.Count, strings.Join(summary, ", "), connectAvg, processingAvg)
+}
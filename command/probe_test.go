@@ -0,0 +1,209 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testProbeCommand(t *testing.T) (*cli.MockUi, *ProbeCommand) {
+	ui := cli.NewMockUi()
+	return ui, &ProbeCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	var durations []time.Duration
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := latencyStats(durations)
+	expected := map[string]float64{"min": 1, "avg": 10.5, "p95": 19, "max": 20}
+	for stat, value := range expected {
+		if stats[stat] != value {
+			t.Errorf("expected %s to be %g, got %g", stat, value, stats[stat])
+		}
+	}
+
+	if stats := latencyStats([]time.Duration{3 * time.Millisecond}); stats["p95"] != 3 {
+		t.Errorf("expected p95 of a single value to be 3, got %g", stats["p95"])
+	}
+}
+
+func TestProbeCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor probe [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"bad_operation",
+			[]string{"-operation", "foo"},
+			"unknown operation: foo",
+			StateUndefined,
+		},
+		{
+			"bad_stat",
+			[]string{"-stat", "p99"},
+			"unknown latency statistic: p99",
+			StateUndefined,
+		},
+		{
+			"bad_count",
+			[]string{"-count", "0"},
+			"The number of operations must be at least 1",
+			StateUndefined,
+		},
+		{
+			"missing_path",
+			[]string{"-operation", "kv-read"},
+			"The path of the secret must be set with '-path'",
+			StateUndefined,
+		},
+		{
+			"seal_status",
+			[]string{"-interval", "0s", "-warning", "10000"},
+			"seal-status latency over 5 requests: min ",
+			StateOk,
+		},
+		{
+			"token_lookup",
+			[]string{"-operation", "token-lookup", "-count", "3", "-interval", "1ms"},
+			"token-lookup latency over 3 requests: ",
+			StateOk,
+		},
+		{
+			"kv_read",
+			[]string{"-operation", "kv-read", "-path", "secret/probe", "-count", "2"},
+			"kv-read latency over 2 requests: ",
+			StateOk,
+		},
+		{
+			"kv_read_missing",
+			[]string{"-operation", "kv-read", "-path", "secret/nosuchsecret"},
+			"error probing kv-read: no secret found at secret/nosuchsecret",
+			StateUndefined,
+		},
+		{
+			"transit",
+			[]string{"-operation", "transit", "-transit-key", "probe", "-count", "2"},
+			"transit latency over 2 requests: ",
+			StateOk,
+		},
+		{
+			"transit_missing_mount",
+			[]string{"-operation", "transit", "-transit-mount", "nosuchmount", "-transit-key", "probe", "-count", "1"},
+			"error probing transit: encryption failed: ",
+			StateUndefined,
+		},
+		{
+			"latency_critical",
+			[]string{"-count", "1", "-stat", "max", "-critical", "@0:"},
+			"seal-status latency over 1 requests: ",
+			StateCritical,
+		},
+		{
+			"nagios_perfdata",
+			[]string{"-output", "nagios", "-count", "2", "-warning", "10000"},
+			";10000;;0 time_max=",
+			StateOk,
+		},
+	}
+
+	t.Run("probe", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, _, closer := testVaultServerUnseal(t)
+				defer closer()
+
+				if _, err := client.Logical().Write("secret/probe", map[string]interface{}{
+					"foo": "bar",
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.Sys().Mount("transit", &api.MountInput{
+					Type: "transit",
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := client.Logical().Write("transit/keys/probe", nil); err != nil {
+					t.Fatal(err)
+				}
+
+				ui, cmd := testProbeCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testProbeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-count", "1"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error probing seal-status: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const textfileDescr = "File the metrics are written to by the prometheus-textfile output"
+
+// prometheusPrefix is the prefix of the names of the exported metrics.
+const prometheusPrefix = "vault_monitor_"
+
+// prometheusMetricName matches the characters not allowed in the Prometheus
+// metric names.
+var prometheusMetricName = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// prometheusServiceLabel matches the service label of a sample.
+var prometheusServiceLabel = regexp.MustCompile(`[{,]service="((?:[^"\\]|\\.)*)"`)
+
+// textfileMutex serializes the updates of the textfiles made by the checks
+// run concurrently by the `batch` and `agent` commands. The updates made by
+// different processes are serialized by lockFile.
+var textfileMutex sync.Mutex
+
+// prometheusUnits maps the units of measurement of the performance data to
+// the suffixes of the metric names and the divisors converting the values to
+// the base units.
+var prometheusUnits = map[string]struct {
+	suffix  string
+	divisor float64
+}{
+	"s":  {"_seconds", 1},
+	"ms": {"_seconds", 1000},
+	"us": {"_seconds", 1000000},
+	"B":  {"_bytes", 1},
+	"%":  {"_percent", 1},
+}
+
+// prometheusLabelValue escapes a label value as required by the Prometheus
+// text exposition format.
+func prometheusLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// prometheusSamples returns the samples reporting the result of a check:
+// its state, the time it has been run and the values of its performance
+// data, all labeled by the service and the command.
+func prometheusSamples(r checkResult) []string {
+	labels := fmt.Sprintf(`{service="%s",command="%s"}`,
+		prometheusLabelValue(r.Service), prometheusLabelValue(r.Command))
+	sample := func(name string, value float64) string {
+		return prometheusPrefix + name + labels + " " + strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	samples := []string{
+		sample("check_state", float64(r.State)),
+		sample("check_timestamp_seconds", float64(r.Timestamp.Unix())),
+	}
+	for _, p := range r.PerfData {
+		name := strings.ToLower(prometheusMetricName.ReplaceAllString(p.Label, "_"))
+		value := p.Value
+		if unit, ok := prometheusUnits[p.UOM]; ok {
+			name += unit.suffix
+			value /= unit.divisor
+		}
+		samples = append(samples, sample(name, value))
+	}
+	return samples
+}
+
+// formatTextfile returns the content of a textfile holding the given samples,
+// grouped by metric.
+func formatTextfile(samples []string) string {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return strings.SplitN(samples[i], "{", 2)[0] < strings.SplitN(samples[j], "{", 2)[0]
+	})
+
+	var buf strings.Builder
+	var metric string
+	for _, s := range samples {
+		if name := strings.SplitN(s, "{", 2)[0]; name != metric {
+			metric = name
+			if metric == prometheusPrefix+"check_state" {
+				fmt.Fprintf(&buf, "# HELP %s State of the check (%d ok, %d warning, %d critical, %d unknown).\n",
+					metric, StateOk, StateWarning, StateCritical, StateUndefined)
+			}
+			fmt.Fprintf(&buf, "# TYPE %s gauge\n", metric)
+		}
+		buf.WriteString(s + "\n")
+	}
+	return buf.String()
+}
+
+// updateTextfile atomically replaces, in the textfile at path, the samples
+// of the service of the check result r, so that the results of several
+// checks can be collected in the same file.
+func updateTextfile(path string, r checkResult) error {
+	textfileMutex.Lock()
+	defer textfileMutex.Unlock()
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("error locking the textfile: %s", err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	service := prometheusLabelValue(r.Service)
+	var samples []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := prometheusServiceLabel.FindStringSubmatch(line); m != nil && m[1] == service {
+			continue
+		}
+		samples = append(samples, line)
+	}
+	samples = append(samples, prometheusSamples(r)...)
+
+	return writeFileAtomic(path, []byte(formatTextfile(samples)), 0644)
+}
+
+// prometheusOutputter returns the Outputter writing the check results to the
+// textfile read by the textfile collector of the Prometheus node_exporter.
+// The results are also displayed as in the default output format.
+func (c *BaseCommand) prometheusOutputter() (*Outputter, error) {
+	if c.Textfile == "" {
+		return nil, fmt.Errorf("the prometheus-textfile output format requires the -textfile flag")
+	}
+	if !strings.HasSuffix(c.Textfile, ".prom") {
+		return nil, fmt.Errorf("the name of the textfile must end with .prom: %s", c.Textfile)
+	}
+
+	o := &Outputter{}
+	textfile := func(state int, display func(string)) func(format string, a ...interface{}) {
+		return func(f string, a ...interface{}) {
+			message := o.message(state, f, a...)
+			display(message + o.formatDetails())
+
+			if err := updateTextfile(c.Textfile, o.result(c, state, message)); err != nil {
+				c.UI.Error(fmt.Sprintf("error writing the textfile %s: %s", c.Textfile, err))
+			}
+		}
+	}
+
+	o.Output = textfile(StateOk, c.UI.Output)
+	o.Warning = textfile(StateWarning, c.UI.Warn)
+	o.Critical = textfile(StateCritical, c.UI.Error)
+	o.Undefined = textfile(StateUndefined, c.UI.Error)
+	return o, nil
+}
@@ -0,0 +1,152 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestPrometheusSamples(t *testing.T) {
+	samples := prometheusSamples(checkResult{
+		Service: `Vault "probe"`,
+		Command: "probe",
+		State:   StateWarning,
+		PerfData: []PerfData{
+			{Label: "time_avg", Value: 12.5, UOM: "ms"},
+			{Label: "ha-active", Value: 1},
+		},
+		Timestamp: time.Unix(1760000000, 0),
+	})
+
+	expected := []string{
+		`vault_monitor_check_state{service="Vault \"probe\"",command="probe"} 1`,
+		`vault_monitor_check_timestamp_seconds{service="Vault \"probe\"",command="probe"} 1760000000`,
+		`vault_monitor_time_avg_seconds{service="Vault \"probe\"",command="probe"} 0.0125`,
+		`vault_monitor_ha_active{service="Vault \"probe\"",command="probe"} 1`,
+	}
+	if got := strings.Join(samples, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("expected the samples\n%s\nto be\n%s", got, strings.Join(expected, "\n"))
+	}
+}
+
+func TestUpdateTextfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.prom")
+	timestamp := time.Unix(1760000000, 0)
+
+	for _, r := range []checkResult{
+		{Service: "Vault status", Command: "status", State: StateCritical, Timestamp: timestamp},
+		{Service: "Vault token", Command: "token-lookup", State: StateOk, Timestamp: timestamp,
+			PerfData: []PerfData{{Label: "ttl", Value: 3600, UOM: "s"}}},
+		{Service: "Vault status", Command: "status", State: StateOk, Timestamp: timestamp},
+	} {
+		if err := updateTextfile(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP vault_monitor_check_state State of the check (0 ok, 1 warning, 2 critical, 3 unknown).
+# TYPE vault_monitor_check_state gauge
+vault_monitor_check_state{service="Vault token",command="token-lookup"} 0
+vault_monitor_check_state{service="Vault status",command="status"} 0
+# TYPE vault_monitor_check_timestamp_seconds gauge
+vault_monitor_check_timestamp_seconds{service="Vault token",command="token-lookup"} 1760000000
+vault_monitor_check_timestamp_seconds{service="Vault status",command="status"} 1760000000
+# TYPE vault_monitor_ttl_seconds gauge
+vault_monitor_ttl_seconds{service="Vault token",command="token-lookup"} 3600
+`
+	if string(data) != expected {
+		t.Errorf("expected the textfile\n%s\nto be\n%s", data, expected)
+	}
+}
+
+func TestStatusCommand_PrometheusTextfile(t *testing.T) {
+	t.Parallel()
+
+	client, _, closer := testVaultServerUnseal(t)
+	defer closer()
+
+	path := filepath.Join(t.TempDir(), "vault.prom")
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"missing_textfile",
+			[]string{"-output", "prometheus-textfile"},
+			"requires the -textfile flag",
+			StateUndefined,
+		},
+		{
+			"bad_textfile_name",
+			[]string{"-output", "prometheus-textfile", "-textfile", path + ".txt"},
+			"must end with .prom",
+			StateUndefined,
+		},
+		{
+			"unsealed",
+			[]string{"-output", "prometheus-textfile", "-textfile", path},
+			" is unsealed",
+			StateOk,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			cmd := &StatusCommand{BaseCommand: newBaseCommand(ui, "status")}
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range []string{
+		`vault_monitor_check_state{service="Vault status",command="status"} 0`,
+		`vault_monitor_sealed{service="Vault status",command="status"} 0`,
+		`vault_monitor_unseal_progress{service="Vault status",command="status"} 0`,
+	} {
+		if !strings.Contains(string(data), sample+"\n") {
+			t.Errorf("expected the textfile %q to contain %q", data, sample)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Default thresholds for the `raft` command.
+// The failure tolerance warning is disabled by default, being equal to the
+// critical threshold: a three-voter cluster has a failure tolerance of 1.
+const (
+	DefaultRaftToleranceWarning  = 1
+	DefaultRaftToleranceCritical = 1
+	DefaultRaftIndexLagWarning   = 1000
+	DefaultRaftIndexLagCritical  = 10000
+	DefaultRaftNonVoterTimeout   = "10m"
+)
+
+const (
+	raftToleranceWarningDescr  = "Warning if the failure tolerance is below this value (default: %d)"
+	raftToleranceCriticalDescr = "Critical if the failure tolerance is below this value (default: %d)"
+	raftIndexLagWarningDescr   = "Warning if a peer applied index lags behind the leader by this value (default: %d)"
+	raftIndexLagCriticalDescr  = "Critical if a peer applied index lags behind the leader by this value (default: %d)"
+	raftNonVoterTimeoutDescr   = "Warning if a healthy non-voter is not promoted within this time (default: %s)"
+	raftStateFileDescr         = "File where the raft leader is saved for detecting leader changes"
+)
+
+// RaftCommand is a CLI Command that holds the attributes of the command `raft`.
+type RaftCommand struct {
+	*BaseCommand
+	ToleranceWarning  int
+	ToleranceCritical int
+	IndexLagWarning   uint64
+	IndexLagCritical  uint64
+	NonVoterTimeout   string
+	StateFile         string
+}
+
+// raftConfigServer is a server entry of `sys/storage/raft/configuration`.
+type raftConfigServer struct {
+	NodeID  string `json:"node_id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader"`
+	Voter   bool   `json:"voter"`
+}
+
+// raftConfiguration is the response of `sys/storage/raft/configuration`.
+type raftConfiguration struct {
+	Config struct {
+		Servers []raftConfigServer `json:"servers"`
+	} `json:"config"`
+}
+
+// raftLeaderState is the data saved in the state file between two runs.
+type raftLeaderState struct {
+	Leader string `json:"leader"`
+	Term   uint64 `json:"term"`
+}
+
+// raftThresholds holds the thresholds used for checking a raft cluster.
+// raftOutcomes are the outcomes of the `raft` command.
+var raftOutcomes = []checkOutcome{
+	{"healthy", StateOk, "the raft cluster is healthy"},
+	{"no-leader", StateCritical, "the raft cluster has no leader"},
+	{"voter-unhealthy", StateCritical, "a voter is unhealthy"},
+	{"non-voter-unhealthy", StateWarning, "a non-voter is unhealthy"},
+	{"non-voter-not-promoted", StateWarning, "a non-voter has not been promoted within -non-voter-timeout"},
+	{"index-lag-warning", StateWarning, "an applied index lag reached -index-lag-warning"},
+	{"index-lag-critical", StateCritical, "an applied index lag reached -index-lag-critical"},
+	{"tolerance-warning", StateWarning, "the failure tolerance is lower than -tolerance-warning"},
+	{"tolerance-critical", StateCritical, "the failure tolerance is lower than -tolerance-critical"},
+	{"autopilot-unhealthy", StateWarning, "autopilot reports the cluster as unhealthy"},
+	{"leader-changed", StateWarning, "the leader changed since the last check (see -state-file)"},
+}
+
+type raftThresholds struct {
+	toleranceWarning  int
+	toleranceCritical int
+	indexLagWarning   uint64
+	indexLagCritical  uint64
+	nonVoterTimeout   time.Duration
+}
+
+// checkRaftCluster evaluates the raft configuration and the autopilot state
+// and returns the resulting state, as given by states for the detected
+// outcomes, along with the list of detected problems.
+func checkRaftCluster(servers []raftConfigServer, state *api.AutopilotState,
+	th raftThresholds, now time.Time, states func(outcome string) int) (int, []string) {
+	var problems []string
+	retCode := states("healthy")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, states(outcome))
+	}
+
+	hasLeader := false
+	for _, s := range servers {
+		if s.Leader {
+			hasLeader = true
+		}
+	}
+	if !hasLeader || state.Leader == "" {
+		report("no-leader", "no raft leader")
+	}
+
+	var leaderIndex uint64
+	if leader, ok := state.Servers[state.Leader]; ok {
+		leaderIndex = leader.LastIndex
+	}
+
+	ids := make([]string, 0, len(state.Servers))
+	for id := range state.Servers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s := state.Servers[id]
+		isVoter := s.Status == "leader" || s.Status == "voter"
+
+		switch {
+		case isVoter && !s.Healthy:
+			report("voter-unhealthy", "voter %s is unhealthy", s.Name)
+		case !isVoter && !s.Healthy:
+			report("non-voter-unhealthy", "non-voter %s is unhealthy", s.Name)
+		case !isVoter && s.NodeType != "read-replica":
+			stableSince, err := time.Parse(time.RFC3339Nano, s.StableSince)
+			if err == nil && now.Sub(stableSince) > th.nonVoterTimeout {
+				report("non-voter-not-promoted", "non-voter %s not promoted since %s",
+					s.Name, stableSince.Format(time.RFC1123))
+			}
+		}
+
+		if id == state.Leader || leaderIndex <= s.LastIndex {
+			continue
+		}
+		if lag := leaderIndex - s.LastIndex; lag >= th.indexLagCritical {
+			report("index-lag-critical", "%s applied index lags behind the leader by %d", s.Name, lag)
+		} else if lag >= th.indexLagWarning {
+			report("index-lag-warning", "%s applied index lags behind the leader by %d", s.Name, lag)
+		}
+	}
+
+	if state.FailureTolerance < th.toleranceCritical {
+		report("tolerance-critical", "failure tolerance is %d", state.FailureTolerance)
+	} else if state.FailureTolerance < th.toleranceWarning {
+		report("tolerance-warning", "failure tolerance is %d", state.FailureTolerance)
+	}
+
+	if !state.Healthy && len(problems) == 0 {
+		report("autopilot-unhealthy", "autopilot reports the cluster as unhealthy")
+	}
+
+	return retCode, problems
+}
+
+// Synopsis returns a short synopsis of the `raft` command.
+func (c *RaftCommand) Synopsis() string {
+	return "Check the health of a Vault Raft (integrated storage) cluster"
+}
+
+// Help returns a long-form help text of the `raft` command.
+func (c *RaftCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor raft [options]
+
+  This command checks the health of a Vault cluster using the integrated
+  storage (raft), by looking at its raft configuration and autopilot state.
+
+    $ hashicorp-vault-monitor raft
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -tolerance-warning=<int>
+       Warning if the failure tolerance (the number of voters that can fail
+       without losing the quorum) is below this value (default: %d). The
+       warning is disabled when this value is not greater than the critical
+       one, as by default: set it to 2 for the clusters of five voters.
+
+    -tolerance-critical=<int>
+       Critical if the failure tolerance is below this value (default: %d).
+
+    -index-lag-warning=<int>
+       Warning if the applied index of a peer lags behind the leader one by
+       at least this value (default: %d).
+
+    -index-lag-critical=<int>
+       Critical if the applied index of a peer lags behind the leader one by
+       at least this value (default: %d).
+
+    -non-voter-timeout=<duration>
+       Warning if a healthy non-voter has not been promoted to voter within
+       this time (default: %s). Read replicas are not checked.
+
+    -state-file=<string>
+       File where the current raft leader and term are saved. If set, a
+       warning is returned when a leader election occurred since the previous
+       run.
+
+  By default, the exit code reflects the raft cluster health:
+
+      - %d - the raft cluster is healthy
+      - %d - a non-voter is unhealthy or stuck, the failure tolerance or the
+            index lag reached the warning threshold, or the leader changed
+      - %d - a voter is unhealthy, there is no leader, or the failure tolerance
+            or the index lag reached the critical threshold
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("leader-changed=ok,no-leader=critical", raftOutcomes),
+		DefaultRaftToleranceWarning,
+		DefaultRaftToleranceCritical,
+		DefaultRaftIndexLagWarning,
+		DefaultRaftIndexLagCritical,
+		DefaultRaftNonVoterTimeout,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `raft` command with the given CLI instance and command-line arguments.
+func (c *RaftCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("raft", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, raftOutcomes)
+	cmdFlags.IntVar(&c.ToleranceWarning, "tolerance-warning",
+		DefaultRaftToleranceWarning,
+		fmt.Sprintf(raftToleranceWarningDescr, DefaultRaftToleranceWarning))
+	cmdFlags.IntVar(&c.ToleranceCritical, "tolerance-critical",
+		DefaultRaftToleranceCritical,
+		fmt.Sprintf(raftToleranceCriticalDescr, DefaultRaftToleranceCritical))
+	cmdFlags.Uint64Var(&c.IndexLagWarning, "index-lag-warning",
+		DefaultRaftIndexLagWarning,
+		fmt.Sprintf(raftIndexLagWarningDescr, DefaultRaftIndexLagWarning))
+	cmdFlags.Uint64Var(&c.IndexLagCritical, "index-lag-critical",
+		DefaultRaftIndexLagCritical,
+		fmt.Sprintf(raftIndexLagCriticalDescr, DefaultRaftIndexLagCritical))
+	cmdFlags.StringVar(&c.NonVoterTimeout, "non-voter-timeout",
+		DefaultRaftNonVoterTimeout,
+		fmt.Sprintf(raftNonVoterTimeoutDescr, DefaultRaftNonVoterTimeout))
+	cmdFlags.StringVar(&c.StateFile, "state-file", "", raftStateFileDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	nonVoterTimeout, err := time.ParseDuration(c.NonVoterTimeout)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	secret, err := client.Logical().Read("sys/storage/raft/configuration")
+	if err != nil {
+		return c.report(out, "error", "error reading the raft configuration: %s", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return c.report(out, "error", "no raft configuration found (is integrated storage in use?)")
+	}
+
+	var config raftConfiguration
+	if err := decodeData(secret.Data, &config); err != nil {
+		return c.report(out, "error", "error decoding the raft configuration: %s", err)
+	}
+
+	state, err := client.Sys().RaftAutopilotState()
+	if err != nil {
+		return c.report(out, "error", "error reading the raft autopilot state: %s", err)
+	}
+	if state == nil {
+		return c.report(out, "error", "no raft autopilot state found (is integrated storage in use?)")
+	}
+
+	retCode, problems := checkRaftCluster(config.Config.Servers, state,
+		raftThresholds{
+			toleranceWarning:  c.ToleranceWarning,
+			toleranceCritical: c.ToleranceCritical,
+			indexLagWarning:   c.IndexLagWarning,
+			indexLagCritical:  c.IndexLagCritical,
+			nonVoterTimeout:   nonVoterTimeout,
+		}, time.Now(), c.state)
+
+	leaderName := state.Leader
+	current := raftLeaderState{Leader: state.Leader}
+	if leader, ok := state.Servers[state.Leader]; ok {
+		leaderName = leader.Name
+		current.Term = leader.LastTerm
+	}
+
+	if c.StateFile != "" {
+		var previous raftLeaderState
+		found, err := readStateFile(c.StateFile, &previous)
+		if err != nil {
+			return c.report(out, "error", "error reading the state file: %s", err)
+		}
+		if found && current.Leader != "" {
+			if previous.Leader != current.Leader {
+				problems = append(problems, fmt.Sprintf("leader changed from %s to %s",
+					previous.Leader, current.Leader))
+				retCode = WorstState(retCode, c.state("leader-changed"))
+			} else if current.Term > previous.Term {
+				problems = append(problems, fmt.Sprintf("leader election occurred (term %d -> %d)",
+					previous.Term, current.Term))
+				retCode = WorstState(retCode, c.state("leader-changed"))
+			}
+		}
+		if current.Leader != "" {
+			if err := writeStateFile(c.StateFile, &current); err != nil {
+				return c.report(out, "error", "error writing the state file: %s", err)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		out.State(retCode)("Raft cluster: %s", strings.Join(problems, "; "))
+		return retCode
+	}
+
+	out.State(retCode)("Raft cluster is healthy (leader: %s, voters: %d, non-voters: %d, failure tolerance: %d)",
+		leaderName,
+		len(state.Voters),
+		len(state.NonVoters),
+		state.FailureTolerance)
+	return retCode
+}
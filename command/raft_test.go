@@ -0,0 +1,367 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+const testRaftConfiguration = `{
+  "data": {
+    "config": {
+      "index": 0,
+      "servers": [
+        {"node_id": "node1", "address": "10.0.0.1:8201", "leader": true, "voter": true},
+        {"node_id": "node2", "address": "10.0.0.2:8201", "leader": false, "voter": true},
+        {"node_id": "node3", "address": "10.0.0.3:8201", "leader": false, "voter": true}
+      ]
+    }
+  }
+}`
+
+const testRaftAutopilotState = `{
+  "data": {
+    "healthy": true,
+    "failure_tolerance": 1,
+    "leader": "node1",
+    "voters": ["node1", "node2", "node3"],
+    "servers": {
+      "node1": {"id": "node1", "name": "node1", "status": "leader", "healthy": true, "last_index": 100, "last_term": 3},
+      "node2": {"id": "node2", "name": "node2", "status": "voter", "healthy": true, "last_index": 100, "last_term": 3},
+      "node3": {"id": "node3", "name": "node3", "status": "voter", "healthy": true, "last_index": 99, "last_term": 3}
+    }
+  }
+}`
+
+func testRaftCommand(t *testing.T) (*cli.MockUi, *RaftCommand) {
+	ui := cli.NewMockUi()
+	return ui, &RaftCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestCheckRaftCluster(t *testing.T) {
+	now := time.Now()
+	th := raftThresholds{
+		toleranceWarning:  DefaultRaftToleranceWarning,
+		toleranceCritical: DefaultRaftToleranceCritical,
+		indexLagWarning:   DefaultRaftIndexLagWarning,
+		indexLagCritical:  DefaultRaftIndexLagCritical,
+		nonVoterTimeout:   10 * time.Minute,
+	}
+
+	config := []raftConfigServer{
+		{NodeID: "node1", Leader: true, Voter: true},
+		{NodeID: "node2", Voter: true},
+		{NodeID: "node3", Voter: true},
+	}
+
+	healthyState := func() *api.AutopilotState {
+		return &api.AutopilotState{
+			Healthy:          true,
+			FailureTolerance: 1,
+			Leader:           "node1",
+			Voters:           []string{"node1", "node2", "node3"},
+			Servers: map[string]*api.AutopilotServer{
+				"node1": {ID: "node1", Name: "node1", Status: "leader", Healthy: true, LastIndex: 5000},
+				"node2": {ID: "node2", Name: "node2", Status: "voter", Healthy: true, LastIndex: 5000},
+				"node3": {ID: "node3", Name: "node3", Status: "voter", Healthy: true, LastIndex: 4990},
+			},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		config []raftConfigServer
+		modify func(*api.AutopilotState)
+		code   int
+		out    string
+	}{
+		{
+			"healthy",
+			config,
+			func(s *api.AutopilotState) {},
+			StateOk,
+			"",
+		},
+		{
+			"no_leader",
+			[]raftConfigServer{{NodeID: "node1", Voter: true}},
+			func(s *api.AutopilotState) {},
+			StateCritical,
+			"no raft leader",
+		},
+		{
+			"unhealthy_voter",
+			config,
+			func(s *api.AutopilotState) {
+				s.Healthy = false
+				s.Servers["node2"].Healthy = false
+			},
+			StateCritical,
+			"voter node2 is unhealthy",
+		},
+		{
+			"unhealthy_non_voter",
+			config,
+			func(s *api.AutopilotState) {
+				s.Servers["node4"] = &api.AutopilotServer{
+					ID: "node4", Name: "node4", Status: "non-voter", LastIndex: 5000,
+				}
+			},
+			StateWarning,
+			"non-voter node4 is unhealthy",
+		},
+		{
+			"stuck_non_voter",
+			config,
+			func(s *api.AutopilotState) {
+				s.Servers["node4"] = &api.AutopilotServer{
+					ID: "node4", Name: "node4", Status: "non-voter", Healthy: true, LastIndex: 5000,
+					StableSince: now.Add(-time.Hour).Format(time.RFC3339Nano),
+				}
+			},
+			StateWarning,
+			"non-voter node4 not promoted since",
+		},
+		{
+			"read_replica_non_voter",
+			config,
+			func(s *api.AutopilotState) {
+				s.Servers["node4"] = &api.AutopilotServer{
+					ID: "node4", Name: "node4", Status: "non-voter", Healthy: true, LastIndex: 5000,
+					StableSince: now.Add(-time.Hour).Format(time.RFC3339Nano),
+					NodeType:    "read-replica",
+				}
+			},
+			StateOk,
+			"",
+		},
+		{
+			"failure_tolerance",
+			config,
+			func(s *api.AutopilotState) { s.FailureTolerance = 0 },
+			StateCritical,
+			"failure tolerance is 0",
+		},
+		{
+			"index_lag_warning",
+			config,
+			func(s *api.AutopilotState) { s.Servers["node3"].LastIndex = 3000 },
+			StateWarning,
+			"node3 applied index lags behind the leader by 2000",
+		},
+		{
+			"index_lag_critical",
+			config,
+			func(s *api.AutopilotState) { s.Servers["node1"].LastIndex = 50000 },
+			StateCritical,
+			"node2 applied index lags behind the leader by 45000",
+		},
+		{
+			"autopilot_unhealthy",
+			config,
+			func(s *api.AutopilotState) { s.Healthy = false },
+			StateWarning,
+			"autopilot reports the cluster as unhealthy",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := healthyState()
+			tc.modify(state)
+
+			code, problems := checkRaftCluster(tc.config, state, th, now,
+				(&BaseCommand{outcomes: raftOutcomes}).state)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d (%v)", code, tc.code, problems)
+			}
+
+			combined := strings.Join(problems, "; ")
+			if tc.out == "" && combined != "" {
+				t.Errorf("expected no problems, got %q", combined)
+			}
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestRaftCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor raft [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"bad_non_voter_timeout",
+			[]string{"-non-voter-timeout", "foo"},
+			"invalid duration",
+			StateUndefined,
+		},
+		{
+			"healthy",
+			[]string{},
+			"Raft cluster is healthy (leader: node1, voters: 3, non-voters: 0, failure tolerance: 1)",
+			StateOk,
+		},
+		{
+			"failure_tolerance_warning",
+			[]string{"-tolerance-warning", "2"},
+			"Raft cluster: failure tolerance is 1",
+			StateWarning,
+		},
+		{
+			"nagios_healthy",
+			[]string{"-output", "nagios"},
+			"vault OK - Raft cluster is healthy",
+			StateOk,
+		},
+	}
+
+	t.Run("raft", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, closer := testVaultServerJSON(t, map[string]string{
+					"/v1/sys/storage/raft/configuration":   testRaftConfiguration,
+					"/v1/sys/storage/raft/autopilot/state": testRaftAutopilotState,
+				})
+				defer closer()
+
+				ui, cmd := testRaftCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("leader_change", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{
+			"/v1/sys/storage/raft/configuration":   testRaftConfiguration,
+			"/v1/sys/storage/raft/autopilot/state": testRaftAutopilotState,
+		})
+		defer closer()
+
+		stateFile := filepath.Join(t.TempDir(), "raft.json")
+		if err := os.WriteFile(stateFile, []byte(`{"leader":"node2","term":2}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testRaftCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-state-file", stateFile})
+		if exp := StateWarning; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "leader changed from node2 to node1"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		// the second run must not report the (already notified) leader change
+		ui, cmd = testRaftCommand(t)
+		cmd.client = client
+
+		if code := cmd.Run([]string{"-state-file", stateFile}); code != StateOk {
+			t.Errorf("expected %d to be %d", code, StateOk)
+		}
+	})
+
+	t.Run("no_integrated_storage", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{})
+		defer closer()
+
+		ui, cmd := testRaftCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "is integrated storage in use?"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testRaftCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error reading the raft configuration: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Thresholds for the WAL lag of the replication secondaries.
+const (
+	DefaultReplicationWALLagWarning  = 1000
+	DefaultReplicationWALLagCritical = 10000
+)
+
+const (
+	replicationTypeDefault        = "all"
+	replicationTypeDescr          = "Replication type to check ('all', 'dr' or 'performance')"
+	replicationWALLagWarningDescr = "Warning if the WAL lag of a secondary reaches this value (default: %d)"
+	replicationWALLagCritDescr    = "Critical if the WAL lag of a secondary reaches this value (default: %d)"
+)
+
+// ReplicationCommand is a CLI Command that holds the attributes of the command `replication`.
+type ReplicationCommand struct {
+	*BaseCommand
+	Type           string
+	WALLagWarning  uint64
+	WALLagCritical uint64
+}
+
+// replicationOutcomes are the outcomes of the `replication` command.
+var replicationOutcomes = []checkOutcome{
+	{"healthy", StateOk, "the replication is healthy"},
+	{"disabled", StateCritical, "the replication type selected by -type is not enabled"},
+	{"bootstrapping", StateWarning, "the replication is bootstrapping"},
+	{"syncing", StateWarning, "the replication is connecting or resynchronizing"},
+	{"failed", StateCritical, "the replication is in an unexpected state"},
+	{"disconnected", StateCritical, "a primary or secondary cluster is not connected"},
+	{"wal-lag-warning", StateWarning, "the WAL lag reached -wal-lag-warning"},
+	{"wal-lag-critical", StateCritical, "the WAL lag reached -wal-lag-critical"},
+}
+
+// replicationNames maps the replication types to their display names.
+var replicationNames = map[string]string{
+	"dr":          "DR",
+	"performance": "Performance",
+}
+
+// checkReplication evaluates the status of one replication type and returns
+// the resulting state, as given by states for the detected outcomes, along
+// with a description of the replication status. If required is set, a
+// disabled replication is reported as a problem.
+func checkReplication(name string, status *api.ReplicationStatusGenericResponse,
+	required bool, lagWarning, lagCritical uint64,
+	states func(outcome string) int) (int, string) {
+	var problems []string
+	retCode := states("healthy")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, states(outcome))
+	}
+
+	mode := status.Mode
+	if mode == "" {
+		mode = "disabled"
+	}
+
+	switch {
+	case mode == "disabled":
+		if required {
+			report("disabled", "replication is not enabled")
+		}
+		return retCode, fmt.Sprintf("%s replication %s%s",
+			name, mode, problemsSuffix(problems))
+	case strings.Contains(mode, "bootstrapping"):
+		report("bootstrapping", "bootstrapping")
+	}
+
+	switch status.State {
+	case "running", "stream-wals", "":
+	case "merkle-diff", "merkle-sync", "connecting":
+		report("syncing", "state is %s", status.State)
+	default:
+		report("failed", "state is %s", status.State)
+	}
+
+	connected := 0
+	for _, s := range status.Secondaries {
+		if s.ConnectionStatus != "" && s.ConnectionStatus != "connected" {
+			report("disconnected", "secondary %s is %s", s.NodeID, s.ConnectionStatus)
+		} else {
+			connected++
+		}
+	}
+	for _, p := range status.Primaries {
+		if p.ConnectionStatus != "" && p.ConnectionStatus != "connected" {
+			report("disconnected", "primary %s is %s", p.APIAddr, p.ConnectionStatus)
+		}
+	}
+
+	var details []string
+	if status.State != "" {
+		details = append(details, "state: "+status.State)
+	}
+	if strings.HasPrefix(mode, "primary") {
+		details = append(details,
+			fmt.Sprintf("known secondaries: %d", len(status.KnownSecondaries)))
+		if len(status.Secondaries) > 0 {
+			details = append(details, fmt.Sprintf("connected: %d", connected))
+		}
+	}
+
+	if strings.HasPrefix(mode, "secondary") && status.LastWAL > 0 {
+		var lag uint64
+		if status.LastWAL > status.LastRemoteWAL {
+			lag = status.LastWAL - status.LastRemoteWAL
+		}
+		details = append(details, fmt.Sprintf("WAL lag: %d", lag))
+
+		if lag >= lagCritical {
+			report("wal-lag-critical", "WAL lag is %d", lag)
+		} else if lag >= lagWarning {
+			report("wal-lag-warning", "WAL lag is %d", lag)
+		}
+	}
+
+	if len(details) > 0 {
+		mode += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	return retCode, fmt.Sprintf("%s replication %s%s",
+		name, mode, problemsSuffix(problems))
+}
+
+// problemsSuffix formats a list of problems to be appended to a message.
+func problemsSuffix(problems []string) string {
+	if len(problems) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(problems, ", ")
+}
+
+// Synopsis returns a short synopsis of the `replication` command.
+func (c *ReplicationCommand) Synopsis() string {
+	return "Check the status of the Vault DR and performance replication"
+}
+
+// Help returns a long-form help text of the `replication` command.
+func (c *ReplicationCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor replication [options]
+
+  This command checks the status of the DR and performance replication
+  (Vault Enterprise), as reported by the node at the given address.
+
+    $ hashicorp-vault-monitor replication -type dr
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -type=<string>
+       The replication type to check. Can be 'all' (default), 'dr' or
+       'performance'. When a single type is selected, the replication must be
+       enabled.
+
+    -wal-lag-warning=<int>
+       Warning if the difference between 'last_wal' and 'last_remote_wal'
+       on a secondary reaches this value (default: %d).
+
+    -wal-lag-critical=<int>
+       Critical if the difference between 'last_wal' and 'last_remote_wal'
+       on a secondary reaches this value (default: %d).
+
+  By default, the exit code reflects the replication status:
+
+      - %d - the replication is working (or disabled, when -type=all)
+      - %d - the cluster is bootstrapping or syncing, or the WAL lag reached
+            the warning threshold
+      - %d - a primary or a secondary is disconnected, the replication is
+            stalled, or the WAL lag reached the critical threshold
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("wal-lag-warning=ok,disabled=warning", replicationOutcomes),
+		DefaultReplicationWALLagWarning,
+		DefaultReplicationWALLagCritical,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `replication` command with the given CLI instance and command-line arguments.
+func (c *ReplicationCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("replication", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, replicationOutcomes)
+	cmdFlags.StringVar(&c.Type, "type", replicationTypeDefault, replicationTypeDescr)
+	cmdFlags.Uint64Var(&c.WALLagWarning, "wal-lag-warning",
+		DefaultReplicationWALLagWarning,
+		fmt.Sprintf(replicationWALLagWarningDescr, DefaultReplicationWALLagWarning))
+	cmdFlags.Uint64Var(&c.WALLagCritical, "wal-lag-critical",
+		DefaultReplicationWALLagCritical,
+		fmt.Sprintf(replicationWALLagCritDescr, DefaultReplicationWALLagCritical))
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	var types []string
+	switch c.Type {
+	case "all":
+		types = []string{"dr", "performance"}
+	case "dr", "performance":
+		types = []string{c.Type}
+	default:
+		out.Undefined("Unknown replication type: %s", c.Type)
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	status, err := client.Sys().ReplicationStatus()
+	if err != nil {
+		return c.report(out, "error", "error checking replication status: %s", err)
+	}
+
+	statuses := map[string]*api.ReplicationStatusGenericResponse{
+		"dr":          &status.DR,
+		"performance": &status.Performance,
+	}
+
+	var messages []string
+	retCode := c.state("healthy")
+
+	for _, t := range types {
+		code, message := checkReplication(replicationNames[t], statuses[t],
+			c.Type != "all", c.WALLagWarning, c.WALLagCritical, c.state)
+		messages = append(messages, message)
+		retCode = WorstState(retCode, code)
+	}
+
+	out.State(retCode)("%s", strings.Join(messages, "; "))
+	return retCode
+}
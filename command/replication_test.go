@@ -0,0 +1,226 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+const testReplicationPrimary = `{
+  "data": {
+    "dr": {
+      "mode": "primary",
+      "state": "running",
+      "last_wal": 1200,
+      "known_secondaries": ["dr-1", "dr-2"],
+      "secondaries": [
+        {"node_id": "dr-1", "api_address": "https://10.0.1.1:8200", "connection_status": "connected"},
+        {"node_id": "dr-2", "api_address": "https://10.0.1.2:8200", "connection_status": "disconnected"}
+      ]
+    },
+    "performance": {
+      "mode": "disabled"
+    }
+  }
+}`
+
+const testReplicationSecondary = `{
+  "data": {
+    "dr": {
+      "mode": "secondary",
+      "state": "stream-wals",
+      "last_wal": 5000,
+      "last_remote_wal": 3500,
+      "primaries": [
+        {"api_address": "https://10.0.0.1:8200", "connection_status": "connected"}
+      ]
+    },
+    "performance": {
+      "mode": "disabled"
+    }
+  }
+}`
+
+func testReplicationCommand(t *testing.T) (*cli.MockUi, *ReplicationCommand) {
+	ui := cli.NewMockUi()
+	return ui, &ReplicationCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestReplicationCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		response string
+		args     []string
+		out      string
+		code     int
+	}{
+		{
+			"help_message",
+			testReplicationPrimary,
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor replication [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			testReplicationPrimary,
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"unknown_type",
+			testReplicationPrimary,
+			[]string{"-type", "foo"},
+			"Unknown replication type: foo",
+			StateUndefined,
+		},
+		{
+			"primary_disconnected_secondary",
+			testReplicationPrimary,
+			[]string{},
+			"DR replication primary (state: running, known secondaries: 2, connected: 1): secondary dr-2 is disconnected; Performance replication disabled",
+			StateCritical,
+		},
+		{
+			"performance_disabled",
+			testReplicationPrimary,
+			[]string{"-type", "performance"},
+			"Performance replication disabled: replication is not enabled",
+			StateCritical,
+		},
+		{
+			"secondary_wal_lag_warning",
+			testReplicationSecondary,
+			[]string{"-type", "dr"},
+			"DR replication secondary (state: stream-wals, WAL lag: 1500): WAL lag is 1500",
+			StateWarning,
+		},
+		{
+			"secondary_wal_lag_critical",
+			testReplicationSecondary,
+			[]string{"-type", "dr", "-wal-lag-critical", "1500"},
+			"WAL lag is 1500",
+			StateCritical,
+		},
+		{
+			"secondary_wal_lag_ok",
+			testReplicationSecondary,
+			[]string{"-wal-lag-warning", "2000"},
+			"DR replication secondary (state: stream-wals, WAL lag: 1500); Performance replication disabled",
+			StateOk,
+		},
+		{
+			"bootstrapping",
+			`{"data":{"dr":{"mode":"bootstrapping"},"performance":{"mode":"disabled"}}}`,
+			[]string{},
+			"DR replication bootstrapping: bootstrapping",
+			StateWarning,
+		},
+		{
+			"secondary_idle",
+			`{"data":{"dr":{"mode":"secondary","state":"idle","primaries":[{"api_address":"https://10.0.0.1:8200","connection_status":"disconnected"}]},"performance":{"mode":"disabled"}}}`,
+			[]string{},
+			"state is idle, primary https://10.0.0.1:8200 is disconnected",
+			StateCritical,
+		},
+		{
+			"nagios_primary",
+			testReplicationPrimary,
+			[]string{"-output", "nagios"},
+			"vault CRITICAL - DR replication primary",
+			StateCritical,
+		},
+	}
+
+	t.Run("replication", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, closer := testVaultServerJSON(t, map[string]string{
+					"/v1/sys/replication/status": tc.response,
+				})
+				defer closer()
+
+				ui, cmd := testReplicationCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("replication_disabled", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		ui, cmd := testReplicationCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateOk; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "DR replication disabled; Performance replication disabled"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testReplicationCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error checking replication status: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
@@ -0,0 +1,402 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hako/durafmt"
+)
+
+// Thresholds for the age of the newest snapshot.
+const (
+	DefaultWarningSnapshotAge  = "26h"
+	DefaultCriticalSnapshotAge = "50h"
+)
+
+const (
+	snapshotDirDescr        = "Directory containing the raft snapshot files"
+	snapshotPatternDefault  = "*.snap"
+	snapshotPatternDescr    = "Shell pattern matching the snapshot file names"
+	snapshotMinSizeDescr    = "Minimal size in bytes of a valid snapshot file"
+	snapshotSkipVerifyDescr = "Do not verify the integrity of the snapshot archive"
+	snapshotAutoConfigDescr = "Name of the automated snapshot configuration to check"
+
+	// snapshotChecksumsFile is the file of a snapshot archive containing
+	// the SHA-256 checksums of the other files.
+	snapshotChecksumsFile = "SHA256SUMS"
+
+	// snapshotSealedChecksumsFile is the sealed copy of the checksums
+	// written by Vault, which is not listed in snapshotChecksumsFile.
+	snapshotSealedChecksumsFile = "SHA256SUMS.sealed"
+)
+
+// SnapshotCommand is a CLI Command that holds the attributes of the command `snapshot`.
+type SnapshotCommand struct {
+	*BaseCommand
+	Dir               string
+	Pattern           string
+	MinSize           int64
+	SkipVerify        bool
+	AutoConfig        string
+	WarningThreshold  string
+	CriticalThreshold string
+}
+
+// snapshotOutcomes are the outcomes of the `snapshot` command.
+var snapshotOutcomes = []checkOutcome{
+	{"fresh", StateOk, "the newest snapshot is recent enough"},
+	{"age-warning", StateWarning, "the newest snapshot is older than -warning"},
+	{"age-critical", StateCritical, "the newest snapshot is older than -critical"},
+	{"missing", StateCritical, "no snapshot is found in -dir"},
+	{"too-small", StateCritical, "the newest snapshot is smaller than -min-size"},
+	{"corrupted", StateCritical, "the newest snapshot archive is corrupted"},
+	{"failed", StateCritical, "the automated snapshot failed"},
+	{"not-taken", StateCritical, "the automated snapshot has not been taken yet"},
+}
+
+// snapshotAutoStatus is the response of `sys/storage/raft/snapshot-auto/status/:name`.
+type snapshotAutoStatus struct {
+	ConsecutiveErrors int    `json:"consecutive_errors_since_last_success"`
+	LastSnapshotEnd   string `json:"last_snapshot_end"`
+	LastSnapshotError string `json:"last_snapshot_error"`
+	LastSnapshotURL   string `json:"last_snapshot_url"`
+}
+
+// newestSnapshot returns the path and the file information of the most
+// recent file in dir matching the given pattern.
+func newestSnapshot(dir, pattern string) (string, os.FileInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var newest string
+	var newestInfo os.FileInfo
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+			newest, newestInfo = path, info
+		}
+	}
+
+	return newest, newestInfo, nil
+}
+
+// verifySnapshotArchive checks that the file at path is a valid raft
+// snapshot archive (a gzipped tarball) whose content matches the checksums
+// listed in its SHA256SUMS file.
+func verifySnapshotArchive(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	hashes := make(map[string]string)
+	var sums []byte
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Name == snapshotChecksumsFile {
+			if sums, err = io.ReadAll(tr); err != nil {
+				return err
+			}
+			continue
+		}
+		if hdr.Name == snapshotSealedChecksumsFile {
+			continue
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return err
+		}
+		hashes[hdr.Name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if sums == nil {
+		return errors.New("missing " + snapshotChecksumsFile + " file")
+	}
+
+	listed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("malformed %s line: %s", snapshotChecksumsFile, scanner.Text())
+		}
+
+		sum, ok := hashes[fields[1]]
+		if !ok {
+			return fmt.Errorf("missing file %s", fields[1])
+		}
+		if sum != strings.ToLower(fields[0]) {
+			return fmt.Errorf("checksum mismatch for %s", fields[1])
+		}
+		listed++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if listed != len(hashes) {
+		return fmt.Errorf("%d files of the archive are not listed in %s",
+			len(hashes)-listed, snapshotChecksumsFile)
+	}
+
+	return nil
+}
+
+// Synopsis returns a short synopsis of the `snapshot` command.
+func (c *SnapshotCommand) Synopsis() string {
+	return "Check the freshness of the Vault raft snapshots"
+}
+
+// Help returns a long-form help text of the `snapshot` command.
+func (c *SnapshotCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor snapshot [options]
+
+  This command checks the age of the newest raft snapshot.
+
+  The snapshots can be looked for in a local directory, in which case the
+  integrity of the newest snapshot archive is also checked:
+
+    $ hashicorp-vault-monitor snapshot -dir /var/backups/vault
+
+  or the status of an automated snapshot configuration (Vault Enterprise) can
+  be read from the Vault server:
+
+    $ hashicorp-vault-monitor snapshot -auto-config daily
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -dir=<string>
+       Directory containing the snapshot files.
+
+    -pattern=<string>
+       Shell pattern matching the snapshot file names (default: '%s').
+
+    -min-size=<int>
+       Minimal size in bytes of a valid snapshot file (default: 1).
+
+    -skip-verify
+       Do not verify the archive and the SHA256SUMS of the newest snapshot.
+
+    -auto-config=<string>
+       Name of the automated snapshot configuration to check, instead of
+       looking for the snapshot files in a local directory.
+
+    -warning=<string>
+       Warning threshold for the snapshot age (default: %s).
+
+    -critical=<string>
+       Critical threshold for the snapshot age (default: %s).
+
+  By default, the exit code reflects the age and the status of the newest
+  snapshot:
+
+      - %d - the newest snapshot is recent and valid
+      - %d - the newest snapshot is older than the warning threshold
+      - %d - the newest snapshot is older than the critical threshold, it is
+            corrupted, or no snapshot has been found
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("age-warning=ok,not-taken=warning", snapshotOutcomes),
+		snapshotPatternDefault,
+		DefaultWarningSnapshotAge,
+		DefaultCriticalSnapshotAge,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// GetThresholds parses the warning and critical thresholds and return their corresponding Duration values
+func (c *SnapshotCommand) GetThresholds() (time.Duration, time.Duration, error) {
+	return parseDurationThresholds(c.WarningThreshold, c.CriticalThreshold)
+}
+
+// Run executes the `snapshot` command with the given CLI instance and command-line arguments.
+func (c *SnapshotCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, snapshotOutcomes)
+	cmdFlags.StringVar(&c.Dir, "dir", "", snapshotDirDescr)
+	cmdFlags.StringVar(&c.Pattern, "pattern", snapshotPatternDefault, snapshotPatternDescr)
+	cmdFlags.Int64Var(&c.MinSize, "min-size", 1, snapshotMinSizeDescr)
+	cmdFlags.BoolVar(&c.SkipVerify, "skip-verify", false, snapshotSkipVerifyDescr)
+	cmdFlags.StringVar(&c.AutoConfig, "auto-config", "", snapshotAutoConfigDescr)
+	cmdFlags.StringVar(&c.WarningThreshold, "warning",
+		DefaultWarningSnapshotAge,
+		fmt.Sprintf(warningDescr, DefaultWarningSnapshotAge))
+	cmdFlags.StringVar(&c.CriticalThreshold, "critical",
+		DefaultCriticalSnapshotAge,
+		fmt.Sprintf(criticalDescr, DefaultCriticalSnapshotAge))
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if (c.Dir == "") == (c.AutoConfig == "") {
+		out.Undefined("Exactly one of the '-dir' and '-auto-config' flags must be set")
+		return StateUndefined
+	}
+
+	warningThreshold, criticalThreshold, err := c.GetThresholds()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	var name, details string
+	var taken time.Time
+
+	if c.Dir != "" {
+		path, info, err := newestSnapshot(c.Dir, c.Pattern)
+		if err != nil {
+			return c.report(out, "error", "error looking for snapshots: %s", err)
+		}
+		if info == nil {
+			return c.report(out, "missing", "no snapshot found in %s", c.Dir)
+		}
+		if info.Size() < c.MinSize {
+			return c.report(out, "too-small", "snapshot %s is too small (%d bytes)", path, info.Size())
+		}
+		if !c.SkipVerify {
+			if err := verifySnapshotArchive(path); err != nil {
+				return c.report(out, "corrupted", "snapshot %s is corrupted: %s", path, err)
+			}
+		}
+		name, taken = path, info.ModTime()
+		details = fmt.Sprintf(", %d bytes", info.Size())
+	} else {
+		client, err := c.Client()
+		if err != nil {
+			out.Undefined(err.Error())
+			return StateUndefined
+		}
+
+		statusPath := "sys/storage/raft/snapshot-auto/status/" + c.AutoConfig
+		secret, err := client.Logical().Read(statusPath)
+		if err != nil {
+			return c.report(out, "error", "error reading %s: %s", statusPath, err)
+		}
+		if secret == nil || secret.Data == nil {
+			return c.report(out, "error", "no automated snapshot configuration named %s", c.AutoConfig)
+		}
+
+		var status snapshotAutoStatus
+		if err := decodeData(secret.Data, &status); err != nil {
+			return c.report(out, "error", "error decoding %s: %s", statusPath, err)
+		}
+
+		if status.ConsecutiveErrors > 0 {
+			return c.report(out, "failed", "automated snapshot %s failed %d times since the last success: %s",
+				c.AutoConfig, status.ConsecutiveErrors, status.LastSnapshotError)
+		}
+
+		taken, err = time.Parse(time.RFC3339Nano, status.LastSnapshotEnd)
+		if err != nil || taken.IsZero() {
+			return c.report(out, "not-taken", "automated snapshot %s has not been taken yet", c.AutoConfig)
+		}
+		name = c.AutoConfig
+		if status.LastSnapshotURL != "" {
+			details = ", " + status.LastSnapshotURL
+		}
+	}
+
+	age := time.Since(taken)
+	ago, _ := durafmt.ParseString(age.Truncate(time.Second).String())
+	message := fmt.Sprintf("newest snapshot %s taken on %s (%s ago%s)",
+		name, taken.Format(time.RFC1123), ago, details)
+
+	switch {
+	case age > criticalThreshold:
+		return c.report(out, "age-critical", "%s", message)
+	case age > warningThreshold:
+		return c.report(out, "age-warning", "%s", message)
+	}
+
+	return c.report(out, "fresh", "%s", message)
+}
@@ -0,0 +1,345 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+// testSnapshotArchive writes a raft snapshot archive at path, laid out as
+// the ones written by Vault (including the sealed checksums file). If
+// corrupt is set, the content of state.bin does not match its checksum.
+func testSnapshotArchive(t *testing.T, path string, corrupt bool, mtime time.Time) {
+	t.Helper()
+
+	files := []struct {
+		name string
+		data string
+	}{
+		{"meta.json", `{"Version":1,"ID":"2-100-1700000000000","Index":100,"Term":2}`},
+		{"state.bin", "raft fsm state"},
+	}
+
+	var sums strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256([]byte(f.data)), f.name)
+	}
+	if corrupt {
+		files[1].data = "tampered raft fsm state"
+	}
+	files = append(files, struct {
+		name string
+		data string
+	}{snapshotChecksumsFile, sums.String()}, struct {
+		name string
+		data string
+	}{snapshotSealedChecksumsFile, "sealed checksums blob"})
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		hdr := &tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gz, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testSnapshotCommand(t *testing.T) (*cli.MockUi, *SnapshotCommand) {
+	ui := cli.NewMockUi()
+	return ui, &SnapshotCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestVerifySnapshotArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.snap")
+	testSnapshotArchive(t, valid, false, time.Now())
+	if err := verifySnapshotArchive(valid); err != nil {
+		t.Errorf("unexpected error for a valid archive: %s", err)
+	}
+
+	corrupted := filepath.Join(dir, "corrupted.snap")
+	testSnapshotArchive(t, corrupted, true, time.Now())
+	if err := verifySnapshotArchive(corrupted); err == nil {
+		t.Errorf("expected an error for a corrupted archive")
+	}
+
+	garbage := filepath.Join(dir, "garbage.snap")
+	if err := os.WriteFile(garbage, []byte("not a snapshot"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySnapshotArchive(garbage); err == nil {
+		t.Errorf("expected an error for a non gzipped file")
+	}
+}
+
+func TestSnapshotCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	cases := []struct {
+		name  string
+		setup func(t *testing.T, dir string)
+		args  []string
+		out   string
+		code  int
+	}{
+		{
+			"help_message",
+			nil,
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor snapshot [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			nil,
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"missing_mode",
+			nil,
+			[]string{},
+			"Exactly one of the '-dir' and '-auto-config' flags must be set",
+			StateUndefined,
+		},
+		{
+			"no_snapshot",
+			nil,
+			[]string{},
+			"no snapshot found in",
+			StateCritical,
+		},
+		{
+			"recent_snapshot",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "old.snap"), false, now.Add(-72*time.Hour))
+				testSnapshotArchive(t, filepath.Join(dir, "new.snap"), false, now.Add(-time.Hour))
+			},
+			[]string{},
+			"new.snap taken on",
+			StateOk,
+		},
+		{
+			"old_snapshot_warning",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), false, now.Add(-30*time.Hour))
+			},
+			[]string{},
+			"vault.snap taken on",
+			StateWarning,
+		},
+		{
+			"old_snapshot_critical",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), false, now.Add(-30*time.Hour))
+			},
+			[]string{"-warning", "12h", "-critical", "24h"},
+			"vault.snap taken on",
+			StateCritical,
+		},
+		{
+			"corrupted_snapshot",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), true, now)
+			},
+			[]string{},
+			"is corrupted: checksum mismatch for state.bin",
+			StateCritical,
+		},
+		{
+			"corrupted_snapshot_skip_verify",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), true, now)
+			},
+			[]string{"-skip-verify"},
+			"vault.snap taken on",
+			StateOk,
+		},
+		{
+			"small_snapshot",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), false, now)
+			},
+			[]string{"-min-size", "1048576"},
+			"is too small",
+			StateCritical,
+		},
+		{
+			"pattern",
+			func(t *testing.T, dir string) {
+				testSnapshotArchive(t, filepath.Join(dir, "vault.snap"), false, now.Add(-72*time.Hour))
+				testSnapshotArchive(t, filepath.Join(dir, "vault.backup"), false, now)
+			},
+			[]string{"-pattern", "*.backup"},
+			"vault.backup taken on",
+			StateOk,
+		},
+		{
+			"bad_threshold",
+			nil,
+			[]string{"-warning", "foo"},
+			"invalid duration",
+			StateUndefined,
+		},
+	}
+
+	t.Run("local", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				dir := t.TempDir()
+				if tc.setup != nil {
+					tc.setup(t, dir)
+				}
+
+				args := tc.args
+				if tc.name != "missing_mode" {
+					args = append([]string{"-dir", dir}, args...)
+				}
+
+				ui, cmd := testSnapshotCommand(t)
+
+				code := cmd.Run(args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	autoCases := []struct {
+		name     string
+		response string
+		out      string
+		code     int
+	}{
+		{
+			"auto_recent",
+			fmt.Sprintf(`{"data":{"consecutive_errors_since_last_success":0,"last_snapshot_end":"%s","last_snapshot_url":"s3://backups/vault.snap"}}`,
+				now.Add(-time.Hour).Format(time.RFC3339Nano)),
+			"newest snapshot daily taken on",
+			StateOk,
+		},
+		{
+			"auto_old",
+			fmt.Sprintf(`{"data":{"consecutive_errors_since_last_success":0,"last_snapshot_end":"%s"}}`,
+				now.Add(-60*time.Hour).Format(time.RFC3339Nano)),
+			"newest snapshot daily taken on",
+			StateCritical,
+		},
+		{
+			"auto_errors",
+			`{"data":{"consecutive_errors_since_last_success":2,"last_snapshot_error":"access denied"}}`,
+			"automated snapshot daily failed 2 times since the last success: access denied",
+			StateCritical,
+		},
+		{
+			"auto_never_taken",
+			`{"data":{"consecutive_errors_since_last_success":0,"last_snapshot_end":""}}`,
+			"automated snapshot daily has not been taken yet",
+			StateCritical,
+		},
+	}
+
+	t.Run("auto", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range autoCases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, closer := testVaultServerJSON(t, map[string]string{
+					"/v1/sys/storage/raft/snapshot-auto/status/daily": tc.response,
+				})
+				defer closer()
+
+				ui, cmd := testSnapshotCommand(t)
+				cmd.client = client
+
+				code := cmd.Run([]string{"-auto-config", "daily"})
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testSnapshotCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-auto-config", "daily"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error reading sys/storage/raft/snapshot-auto/status/daily: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
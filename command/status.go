@@ -19,6 +19,9 @@ package command
 import (
 	"flag"
 	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/vault/api"
 )
 
 // StatusCommand is a CLI Command that holds the attributes of the command `status`.
@@ -26,6 +29,38 @@ type StatusCommand struct {
 	*BaseCommand
 }
 
+// sealPerfData returns the performance data reporting the seal status.
+func sealPerfData(status *api.SealStatusResponse) []PerfData {
+	sealed := 0.0
+	if status.Sealed {
+		sealed = 1
+	}
+	return []PerfData{
+		{Label: "sealed", Value: sealed, Min: "0", Max: "1"},
+		{Label: "unseal_progress", Value: float64(status.Progress), Min: "0", Max: strconv.Itoa(status.T)},
+	}
+}
+
+// statusOutcomes are the outcomes of the `status` command.
+var statusOutcomes = []checkOutcome{
+	{"unsealed", StateOk, "the Vault server is unsealed"},
+	{"sealed", StateCritical, "the Vault server is sealed"},
+}
+
+// sealData returns the seal status data the -format template is evaluated
+// against.
+func sealData(status *api.SealStatusResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"ClusterName": status.ClusterName,
+		"ClusterID":   status.ClusterID,
+		"Version":     status.Version,
+		"Sealed":      status.Sealed,
+		"Progress":    status.Progress,
+		"Threshold":   status.T,
+		"Shares":      status.N,
+	}
+}
+
 // Synopsis returns a short synopsis of the `status` command.
 func (c *StatusCommand) Synopsis() string {
 	return "Returns the Vault status (sealed/unsealed)"
@@ -46,26 +81,41 @@ Usage: hashicorp-vault-monitor status [options]
        Address of the Vault server. The default is https://127.0.0.1:8200. This
        can also be specified via the VAULT_ADDR environment variable.
 
-    -output=<string>
-       Specify an output format. Can be 'default' or 'nagios'.
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable. The seal status endpoint does not
+       require authentication.
+
+%s
+
+    -format=<string>
+       Go text/template building the output message in place of the default
+       one. The template is evaluated against the fields .ClusterName,
+       .ClusterID, .Version, .Sealed, .Progress (of the unseal process),
+       .Threshold and .Shares, the default message .Message and the label of
+       the state .State. For instance:
+
+         -format='{{.State}}: {{.Message}} - see https://wiki.example.com/vault'
+
+%s
 
     -unknown-as-critical
-       Every unknown error is treated as critical.
+       Deprecated, same as -state error=critical.
 
     -sealed-as-warning
-       Sealed status is treated as warning.
+       Deprecated, same as -state sealed=warning.
 
-  The exit code reflects the seal status:
+  By default, the exit code reflects the seal status:
 
       - %d - the vault node is unsealed
       - %d - the vault node is sealed
       - %d - an error occurred
 
-  The last case will be merged into the second one if -unknown-as-critical is selected.
-
   For a full list of examples, please see the online documentation.
 `
 	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("sealed=warning,error=critical", statusOutcomes),
 		StateOk, StateCritical, StateUndefined)
 }
 
@@ -74,7 +124,10 @@ func (c *StatusCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("status", flag.ContinueOnError)
 	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
 	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
-	cmdFlags.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.MessageTemplate, "format", "", messageTemplateDescr)
+	c.addStateFlag(cmdFlags, statusOutcomes)
 	cmdFlags.BoolVar(&c.UnknownAsCritical, "unknown-as-critical", false, unknownAsCriticalDescr)
 	cmdFlags.BoolVar(&c.SealedAsWarning, "sealed-as-warning", false, sealedAsWarningDescr)
 
@@ -103,31 +156,18 @@ func (c *StatusCommand) Run(args []string) int {
 
 	status, err := client.Sys().SealStatus()
 	if err != nil {
-		if c.UnknownAsCritical {
-			out.Critical("error checking seal status: %s", err)
-			return StateCritical
-		} else {
-			out.Undefined("error checking seal status: %s", err)
-			return StateUndefined
-		}
+		return c.report(out, "error", "error checking seal status: %s", err)
 	}
 
+	out.AddPerfData(sealPerfData(status)...)
+	out.SetData(sealData(status))
+
 	if status.Sealed {
-		if c.SealedAsWarning {
-			out.Warning("Vault (%s) is sealed! Unseal Progress: %d/%d",
-				status.ClusterName,
-				status.Progress,
-				status.T)
-			return StateWarning
-		} else {
-			out.Critical("Vault (%s) is sealed! Unseal Progress: %d/%d",
-				status.ClusterName,
-				status.Progress,
-				status.T)
-			return StateCritical
-		}
+		return c.report(out, "sealed", "Vault (%s) is sealed! Unseal Progress: %d/%d",
+			status.ClusterName,
+			status.Progress,
+			status.T)
 	}
 
-	out.Output("Vault (%s) is unsealed", status.ClusterName)
-	return StateOk
+	return c.report(out, "unsealed", "Vault (%s) is unsealed", status.ClusterName)
 }
@@ -74,6 +74,30 @@ func TestStatusCommand_Run(t *testing.T) {
 			" is unsealed",
 			StateOk,
 		},
+		{
+			"custom_format",
+			[]string{"-format", "{{.State}}: {{if .Sealed}}sealed{{else}}unsealed{{end}} ({{.Threshold}}/{{.Shares}} keys)"},
+			"OK: unsealed (3/3 keys)",
+			StateOk,
+		},
+		{
+			"unsealed_as_warning",
+			[]string{"-state", "unsealed=warning"},
+			" is unsealed",
+			StateWarning,
+		},
+		{
+			"unknown_outcome",
+			[]string{"-state", "no-leader=critical"},
+			"Unknown outcome: no-leader (expected one of: error, unsealed, sealed)",
+			StateUndefined,
+		},
+		{
+			"invalid_state",
+			[]string{"-state", "sealed=fatal"},
+			"invalid value \"sealed=fatal\" for flag -state",
+			StateUndefined,
+		},
 	}
 
 	t.Run("status", func(t *testing.T) {
@@ -103,21 +127,36 @@ func TestStatusCommand_Run(t *testing.T) {
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
-		client, closer := testVaultServerBad(t)
-		defer closer()
+		failures := []struct {
+			name string
+			args []string
+			code int
+		}{
+			{"default", []string{}, StateUndefined},
+			{"error_as_critical", []string{"-state", "error=critical"}, StateCritical},
+			{"unknown_as_critical", []string{"-unknown-as-critical"}, StateCritical},
+			{"explicit_state_wins", []string{"-unknown-as-critical", "-state", "error=warning"}, StateWarning},
+		}
+
+		for _, tc := range failures {
+			t.Run(tc.name, func(t *testing.T) {
+				client, closer := testVaultServerBad(t)
+				defer closer()
 
-		ui, cmd := testStatusCommand(t)
-		cmd.client = client
+				ui, cmd := testStatusCommand(t)
+				cmd.client = client
 
-		code := cmd.Run([]string{})
-		if exp := StateUndefined; code != exp {
-			t.Errorf("expected %d to be %d", code, exp)
-		}
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
 
-		expected := "error checking seal status: "
-		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
-		if !strings.Contains(combined, expected) {
-			t.Errorf("expected %q to contain %q", combined, expected)
+				expected := "error checking seal status: "
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, expected) {
+					t.Errorf("expected %q to contain %q", combined, expected)
+				}
+			})
 		}
 	})
 }
@@ -0,0 +1,290 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/madrisan/hashicorp-vault-monitor/version"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultOTLPProtocol is the default protocol used for exporting the
+// telemetry data to the OpenTelemetry collector.
+const DefaultOTLPProtocol = "grpc"
+
+const (
+	otlpEndpointDescr = "URL of the OpenTelemetry collector the check results and " +
+		"the traces of the Vault API calls are exported to"
+	otlpProtocolDescr = "Protocol used for exporting to the OpenTelemetry collector, " +
+		"'grpc' or 'http' (default: %s)"
+)
+
+// telemetryName is the name of the instrumentation scope, and of the service
+// in the OpenTelemetry resource.
+const telemetryName = "hashicorp-vault-monitor"
+
+// otelUnits maps the units of measurement of the performance data to the
+// UCUM units used by OpenTelemetry.
+var otelUnits = map[string]string{
+	"s":  "s",
+	"ms": "ms",
+	"us": "us",
+	"B":  "By",
+	"%":  "%",
+}
+
+// spanExporter records the errors occurred while exporting the spans, which
+// the span processors do not report back.
+type spanExporter struct {
+	sdktrace.SpanExporter
+
+	mu  sync.Mutex
+	err error
+}
+
+// ExportSpans exports the spans and records the error, if any.
+func (e *spanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	if err != nil {
+		e.mu.Lock()
+		e.err = err
+		e.mu.Unlock()
+	}
+	return err
+}
+
+// telemetry exports the result of a check as OpenTelemetry metrics and the
+// Vault API calls made by the check as the spans of a trace.
+type telemetry struct {
+	ctx    context.Context
+	span   trace.Span
+	start  time.Time
+	spans  *spanExporter
+	tracer *sdktrace.TracerProvider
+	meter  *sdkmetric.MeterProvider
+}
+
+// newTelemetry sets up the export of the telemetry data of the command c
+// and starts the span of the check.
+func newTelemetry(c *BaseCommand) (*telemetry, error) {
+	u, err := url.Parse(c.OTLPEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %s (expected an http or https URL)", c.OTLPEndpoint)
+	}
+
+	ctx := context.Background()
+
+	var spanExp sdktrace.SpanExporter
+	var metricExp sdkmetric.Exporter
+
+	switch c.OTLPProtocol {
+	case "grpc":
+		if spanExp, err = otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpointURL(c.OTLPEndpoint)); err != nil {
+			return nil, err
+		}
+		if metricExp, err = otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(c.OTLPEndpoint)); err != nil {
+			return nil, err
+		}
+	case "http":
+		base := strings.TrimRight(c.OTLPEndpoint, "/")
+		if spanExp, err = otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(base+"/v1/traces")); err != nil {
+			return nil, err
+		}
+		if metricExp, err = otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(base+"/v1/metrics")); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol: %s (expected 'grpc' or 'http')", c.OTLPProtocol)
+	}
+
+	// the export errors are reported when the check ends
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", telemetryName),
+		attribute.String("service.version", version.GetVersion().VersionNumber()))
+
+	t := &telemetry{
+		start: time.Now(),
+		spans: &spanExporter{SpanExporter: spanExp},
+	}
+	t.tracer = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(t.spans),
+		sdktrace.WithResource(res))
+	t.meter = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)),
+		sdkmetric.WithResource(res))
+
+	t.ctx, t.span = t.tracer.Tracer(telemetryName).Start(ctx, c.Service(),
+		trace.WithAttributes(
+			attribute.String("vault_monitor.command", c.CommandName),
+			attribute.String("vault_monitor.service", c.Service())))
+
+	return t, nil
+}
+
+// tracedTransport is an HTTP transport recording the requests as child spans
+// of the span of the check.
+type tracedTransport struct {
+	span trace.Span
+	next http.RoundTripper
+}
+
+// RoundTrip executes a single HTTP transaction in the context of the check.
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(trace.ContextWithSpan(req.Context(), t.span)))
+}
+
+// traceClient returns a copy of the Vault client recording its requests as
+// spans. The W3C trace context is propagated to the Vault server, so that the
+// spans can be correlated with the server-side traces.
+func (t *telemetry) traceClient(client *api.Client) (*api.Client, error) {
+	config := client.CloneConfig()
+	config.HttpClient.Transport = &tracedTransport{
+		span: t.span,
+		next: otelhttp.NewTransport(config.HttpClient.Transport,
+			otelhttp.WithTracerProvider(t.tracer),
+			otelhttp.WithPropagators(propagation.TraceContext{}),
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + r.URL.Path
+			})),
+	}
+
+	traced, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	traced.SetToken(client.Token())
+	traced.SetHeaders(client.Headers())
+
+	return traced, nil
+}
+
+// finish records the result r of the check, ends its span and exports all
+// the telemetry data.
+func (t *telemetry) finish(r checkResult) error {
+	meter := t.meter.Meter(telemetryName)
+	attrs := metric.WithAttributes(
+		attribute.String("service", r.Service),
+		attribute.String("command", r.Command))
+
+	var errs []string
+	record := func(name, description, unit string, value float64) {
+		gauge, err := meter.Float64Gauge("vault_monitor."+name,
+			metric.WithDescription(description), metric.WithUnit(unit))
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		gauge.Record(t.ctx, value, attrs)
+	}
+
+	record("check.state", fmt.Sprintf("State of the check (%d ok, %d warning, %d critical, %d unknown)",
+		StateOk, StateWarning, StateCritical, StateUndefined), "", float64(r.State))
+	record("check.duration", "Duration of the check", "s", time.Since(t.start).Seconds())
+	for _, p := range r.PerfData {
+		record(prometheusMetricName.ReplaceAllString(p.Label, "_"), "", otelUnits[p.UOM], p.Value)
+	}
+
+	t.span.SetAttributes(
+		attribute.Int("vault_monitor.state", r.State),
+		attribute.String("vault_monitor.state_name", r.StateName))
+	if r.State == StateOk {
+		t.span.SetStatus(codes.Ok, "")
+	} else {
+		t.span.SetStatus(codes.Error, r.Message)
+	}
+	t.span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	if err := t.tracer.Shutdown(ctx); err != nil {
+		errs = append(errs, err.Error())
+	}
+	t.spans.mu.Lock()
+	if t.spans.err != nil {
+		errs = append(errs, t.spans.err.Error())
+	}
+	t.spans.mu.Unlock()
+	if err := t.meter.Shutdown(ctx); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// exportTelemetry returns the Outputter o, also exporting the check result
+// and the traces of the Vault API calls to the OpenTelemetry collector.
+func (c *BaseCommand) exportTelemetry(o *Outputter) (*Outputter, error) {
+	t, err := newTelemetry(c)
+	if err != nil {
+		return nil, err
+	}
+	c.telemetry = t
+
+	// the test client
+	if c.client != nil {
+		if c.client, err = t.traceClient(c.client); err != nil {
+			return nil, err
+		}
+	}
+
+	export := func(state int, report func(format string, a ...interface{})) func(format string, a ...interface{}) {
+		return func(format string, a ...interface{}) {
+			report(format, a...)
+			if err := t.finish(o.result(c, state, o.message(state, format, a...))); err != nil {
+				c.UI.Error(fmt.Sprintf("error exporting the telemetry data: %s", err))
+			}
+		}
+	}
+
+	o.Output = export(StateOk, o.Output)
+	o.Warning = export(StateWarning, o.Warning)
+	o.Critical = export(StateCritical, o.Critical)
+	o.Undefined = export(StateUndefined, o.Undefined)
+	return o, nil
+}
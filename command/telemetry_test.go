@@ -0,0 +1,169 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	metricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testOTLPCollector returns an OTLP/HTTP collector storing the spans and
+// the metrics it receives.
+func testOTLPCollector(t *testing.T) (*httptest.Server, *tracepb.ExportTraceServiceRequest, *metricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	traces := &tracepb.ExportTraceServiceRequest{}
+	metrics := &metricspb.ExportMetricsServiceRequest{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/traces":
+			req := &tracepb.ExportTraceServiceRequest{}
+			err = proto.Unmarshal(body, req)
+			traces.ResourceSpans = append(traces.ResourceSpans, req.ResourceSpans...)
+		case "/v1/metrics":
+			req := &metricspb.ExportMetricsServiceRequest{}
+			err = proto.Unmarshal(body, req)
+			metrics.ResourceMetrics = append(metrics.ResourceMetrics, req.ResourceMetrics...)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, traces, metrics
+}
+
+func TestTelemetry(t *testing.T) {
+	t.Parallel()
+
+	client, _, closer := testVaultServerUnseal(t)
+	defer closer()
+
+	server, traces, metrics := testOTLPCollector(t)
+
+	ui := cli.NewMockUi()
+	cmd := &StatusCommand{BaseCommand: newBaseCommand(ui, "status")}
+	cmd.client = client
+
+	args := []string{"-otlp-endpoint", server.URL, "-otlp-protocol", "http"}
+	if code := cmd.Run(args); code != StateOk {
+		t.Fatalf("expected %d to be %d (%s)", code, StateOk, ui.ErrorWriter.String())
+	}
+
+	combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+	if !strings.Contains(combined, " is unsealed") || strings.Contains(combined, "error") {
+		t.Errorf("unexpected output: %q", combined)
+	}
+
+	spans := make(map[string][]byte)
+	var checkSpanID []byte
+	for _, rs := range traces.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				spans[span.Name] = span.ParentSpanId
+				if span.Name == "Vault status" {
+					checkSpanID = span.SpanId
+				}
+			}
+		}
+	}
+	if checkSpanID == nil {
+		t.Fatalf("expected the span of the check in %v", spans)
+	}
+	if parent, ok := spans["GET /v1/sys/seal-status"]; !ok || !bytes.Equal(parent, checkSpanID) {
+		t.Errorf("expected the span of the Vault API call to be a child of the check span: %v", spans)
+	}
+
+	values := make(map[string]float64)
+	for _, rm := range metrics.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if points := m.GetGauge().GetDataPoints(); len(points) > 0 {
+					values[m.Name] = points[0].GetAsDouble()
+				}
+			}
+		}
+	}
+	for name, expected := range map[string]float64{
+		"vault_monitor.check.state": float64(StateOk),
+		"vault_monitor.sealed":      0,
+	} {
+		if value, ok := values[name]; !ok || value != expected {
+			t.Errorf("expected the metric %s to be %v, got: %v", name, expected, values)
+		}
+	}
+}
+
+func TestTelemetry_BadFlags(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		out  string
+	}{
+		{
+			"bad_endpoint",
+			[]string{"-otlp-endpoint", "127.0.0.1:4317"},
+			"invalid OTLP endpoint",
+		},
+		{
+			"unknown_protocol",
+			[]string{"-otlp-endpoint", "http://127.0.0.1:4317", "-otlp-protocol", "thrift"},
+			"unknown OTLP protocol: thrift",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			cmd := &StatusCommand{BaseCommand: newBaseCommand(ui, "status")}
+
+			if code := cmd.Run(tc.args); code != StateUndefined {
+				t.Errorf("expected %d to be %d", code, StateUndefined)
+			}
+			if combined := ui.ErrorWriter.String(); !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// nagiosRange is a threshold range as defined by the Nagios plugin
+// development guidelines:
+//
+//	10      alert if the value is < 0 or > 10
+//	10:     alert if the value is < 10
+//	~:10    alert if the value is > 10
+//	10:20   alert if the value is < 10 or > 20
+//	@10:20  alert if the value is >= 10 and <= 20
+type nagiosRange struct {
+	spec   string
+	start  float64
+	end    float64
+	inside bool
+}
+
+// parseNagiosRange parses a threshold range in the Nagios format.
+func parseNagiosRange(spec string) (*nagiosRange, error) {
+	r := &nagiosRange{spec: spec, end: math.Inf(1)}
+
+	s := strings.TrimSpace(spec)
+	if strings.HasPrefix(s, "@") {
+		r.inside = true
+		s = s[1:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("invalid threshold range: '%s'", spec)
+	}
+
+	start, end := "0", s
+	if i := strings.Index(s, ":"); i >= 0 {
+		start, end = s[:i], s[i+1:]
+	}
+
+	var err error
+	switch start {
+	case "~":
+		r.start = math.Inf(-1)
+	case "":
+		r.start = 0
+	default:
+		if r.start, err = strconv.ParseFloat(start, 64); err != nil {
+			return nil, fmt.Errorf("invalid threshold range: '%s'", spec)
+		}
+	}
+	if end != "" {
+		if r.end, err = strconv.ParseFloat(end, 64); err != nil {
+			return nil, fmt.Errorf("invalid threshold range: '%s'", spec)
+		}
+	}
+	if r.start > r.end {
+		return nil, fmt.Errorf("invalid threshold range: '%s' (start > end)", spec)
+	}
+
+	return r, nil
+}
+
+// alert returns true if the value v must raise an alert.
+func (r *nagiosRange) alert(v float64) bool {
+	outside := v < r.start || v > r.end
+	if r.inside {
+		return !outside
+	}
+	return outside
+}
+
+// String returns the range as given by the user.
+func (r *nagiosRange) String() string {
+	return r.spec
+}
+
+// parseOptionalRange parses the given threshold range, if not empty.
+func parseOptionalRange(spec string) (*nagiosRange, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	return parseNagiosRange(spec)
+}
+
+// rangeOutcomes are the outcomes of the checks of a value against the
+// warning and critical ranges.
+var rangeOutcomes = []checkOutcome{
+	{"within-thresholds", StateOk, "the value does not trigger any threshold"},
+	{"warning-threshold", StateWarning, "the value triggers the -warning threshold"},
+	{"critical-threshold", StateCritical, "the value triggers the -critical threshold"},
+}
+
+// rangeOutcome returns the outcome of the check of the value v against the
+// (optional) warning and critical ranges.
+func rangeOutcome(v float64, warning, critical *nagiosRange) string {
+	switch rangeState(v, warning, critical) {
+	case StateCritical:
+		return "critical-threshold"
+	case StateWarning:
+		return "warning-threshold"
+	}
+	return "within-thresholds"
+}
+
+// rangeState returns the state of the value v checked against the
+// (optional) warning and critical ranges.
+func rangeState(v float64, warning, critical *nagiosRange) int {
+	switch {
+	case critical != nil && critical.alert(v):
+		return StateCritical
+	case warning != nil && warning.alert(v):
+		return StateWarning
+	}
+	return StateOk
+}
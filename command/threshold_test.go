@@ -0,0 +1,85 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"testing"
+)
+
+func TestParseNagiosRange(t *testing.T) {
+	cases := []struct {
+		spec    string
+		alert   []float64
+		noAlert []float64
+	}{
+		{"10", []float64{-1, 10.5, 11}, []float64{0, 5, 10}},
+		{"10:", []float64{-1, 9.9}, []float64{10, 1e9}},
+		{"~:10", []float64{10.1, 100}, []float64{-1e9, 0, 10}},
+		{"10:20", []float64{9, 21}, []float64{10, 15, 20}},
+		{"@10:20", []float64{10, 15, 20}, []float64{9, 21}},
+		{":5", []float64{-1, 6}, []float64{0, 5}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			r, err := parseNagiosRange(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range tc.alert {
+				if !r.alert(v) {
+					t.Errorf("expected %g to raise an alert for the range '%s'", v, tc.spec)
+				}
+			}
+			for _, v := range tc.noAlert {
+				if r.alert(v) {
+					t.Errorf("expected %g to not raise an alert for the range '%s'", v, tc.spec)
+				}
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "@", "foo", "10:foo", "20:10"} {
+		if _, err := parseNagiosRange(spec); err == nil {
+			t.Errorf("expected an error for the range '%s'", spec)
+		}
+	}
+}
+
+func TestRangeState(t *testing.T) {
+	warning, _ := parseNagiosRange("100")
+	critical, _ := parseNagiosRange("200")
+
+	cases := []struct {
+		value float64
+		code  int
+	}{
+		{50, StateOk},
+		{150, StateWarning},
+		{250, StateCritical},
+	}
+
+	for _, tc := range cases {
+		if code := rangeState(tc.value, warning, critical); code != tc.code {
+			t.Errorf("expected %d to be %d for the value %g", code, tc.code, tc.value)
+		}
+	}
+
+	if code := rangeState(1e9, nil, nil); code != StateOk {
+		t.Errorf("expected %d to be %d without thresholds", code, StateOk)
+	}
+}
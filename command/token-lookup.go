@@ -17,8 +17,14 @@
 package command
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hako/durafmt"
@@ -31,11 +37,491 @@ const (
 	DefaultCriticalTokenExpiration = "72h"
 )
 
+// Default thresholds for the periodic tokens, as percentages of the period
+// elapsed since the last renewal.
+const (
+	DefaultPeriodWarning  = 50
+	DefaultPeriodCritical = 75
+)
+
+// DefaultTokenLookupConcurrency is the default number of concurrent lookups
+// of the token accessors.
+const DefaultTokenLookupConcurrency = 10
+
+const (
+	tokenAllAccessorsDescr  = "Lookup all the token accessors listed by auth/token/accessors"
+	tokenAccessorsFileDescr = "File containing the token accessors to lookup, one per line"
+	tokenConcurrencyDescr   = "Number of concurrent lookups of the token accessors (default: %d)"
+
+	tokenPeriodWarningDescr  = "Warning threshold for periodic tokens, in percentage of the period elapsed since the last renewal (default: %d)"
+	tokenPeriodCriticalDescr = "Critical threshold for periodic tokens, in percentage of the period elapsed since the last renewal (default: %d)"
+	tokenRenewBelowDescr     = "Renew the token when it expires in less than the given duration"
+
+	tokenPoliciesDescr     = "Comma separated list of the policies the token must carry"
+	tokenOrphanDescr       = "Whether the token must ('true') or must not ('false') be an orphan token"
+	tokenRenewableDescr    = "Whether the token must ('true') or must not ('false') be renewable"
+	tokenPeriodicDescr     = "Whether the token must ('true') or must not ('false') be a periodic token"
+	tokenMinUsesDescr      = "Minimum number of uses left for a token with a limited number of uses"
+	tokenCapabilitiesDescr = "Capabilities the token must have on a path, as PATH=CAP1,CAP2 (can be repeated)"
+)
+
 // TokenLookupCommand is a CLI Command that holds the attributes of the command `token-lookup`.
 type TokenLookupCommand struct {
 	*BaseCommand
 	WarningThreshold  string
 	CriticalThreshold string
+	AllAccessors      bool
+	AccessorsFile     string
+	Concurrency       int
+	PeriodWarning     int
+	PeriodCritical    int
+	RenewBelow        string
+	Policies          string
+	Orphan            string
+	Renewable         string
+	Periodic          string
+	MinUses           int
+	Capabilities      stringSliceFlag
+}
+
+// tokenInfo holds the information about a token looked up by its accessor.
+type tokenInfo struct {
+	Accessor         string   `json:"accessor"`
+	DisplayName      string   `json:"display_name"`
+	Policies         []string `json:"policies"`
+	IdentityPolicies []string `json:"identity_policies"`
+	EntityID         string   `json:"entity_id"`
+	Path             string   `json:"path"`
+	ExpireTime       string   `json:"expire_time"`
+	Renewable        bool     `json:"renewable"`
+	Orphan           bool     `json:"orphan"`
+	Period           int64    `json:"period"`
+	NumUses          int      `json:"num_uses"`
+	CreationTime     int64    `json:"creation_time"`
+	CreationTTL      int64    `json:"creation_ttl"`
+	LastRenewalTime  int64    `json:"last_renewal_time"`
+
+	err     error
+	creator string
+	expires time.Time
+}
+
+// readAccessorsFile returns the token accessors listed in the file at path,
+// one per line. Empty lines and lines starting with a '#' are ignored.
+func readAccessorsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var accessors []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		accessors = append(accessors, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return accessors, nil
+}
+
+// listAccessors returns all the token accessors (this requires the sudo
+// capability on auth/token/accessors).
+func listAccessors(client *api.Client) ([]string, error) {
+	secret, err := client.Logical().List("auth/token/accessors")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var data struct {
+		Keys []string `json:"keys"`
+	}
+	if err := decodeData(secret.Data, &data); err != nil {
+		return nil, err
+	}
+	return data.Keys, nil
+}
+
+// lookupAccessors concurrently looks up the given token accessors and
+// resolves the name of the entities the tokens belong to.
+func lookupAccessors(client *api.Client, accessors []string, concurrency int) []tokenInfo {
+	tokens := make([]tokenInfo, len(accessors))
+
+	var entities sync.Map
+	entityName := func(id string) string {
+		if name, ok := entities.Load(id); ok {
+			return name.(string)
+		}
+		name := id
+		if secret, err := client.Logical().Read("identity/entity/id/" + id); err == nil &&
+			secret != nil && secret.Data["name"] != nil {
+			name = fmt.Sprint(secret.Data["name"])
+		}
+		entities.Store(id, name)
+		return name
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tokens[i] = lookupAccessor(client, accessors[i], entityName)
+			}
+		}()
+	}
+	for i := range accessors {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return tokens
+}
+
+// lookupAccessor looks up a single token accessor.
+func lookupAccessor(client *api.Client, accessor string, entityName func(string) string) tokenInfo {
+	token := tokenInfo{Accessor: accessor}
+
+	secret, err := client.Auth().Token().LookupAccessor(accessor)
+	if err == nil && (secret == nil || secret.Data == nil) {
+		err = fmt.Errorf("no data returned")
+	}
+	if err == nil {
+		err = decodeData(secret.Data, &token)
+	}
+	if err == nil {
+		token.expires, err = token.expiration()
+	}
+	if err != nil {
+		token.err = err
+		return token
+	}
+
+	switch {
+	case token.EntityID != "":
+		token.creator = entityName(token.EntityID)
+	case token.Path != "":
+		token.creator = token.Path
+	default:
+		token.creator = "unknown"
+	}
+
+	return token
+}
+
+// tokenAssertions holds the properties a token is expected to have.
+// The nil boolean pointers are not checked.
+type tokenAssertions struct {
+	policies     []string
+	orphan       *bool
+	renewable    *bool
+	periodic     *bool
+	minUses      int
+	capabilities map[string][]string
+	paths        []string
+}
+
+// parseOptionalBool parses the value of a boolean flag that may be unset.
+func parseOptionalBool(name, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s' for the flag -%s", value, name)
+	}
+	return &b, nil
+}
+
+// parseCapabilities parses the PATH=CAP1,CAP2 capability specifications.
+func parseCapabilities(specs []string) (map[string][]string, []string, error) {
+	capabilities := make(map[string][]string)
+	var paths []string
+	for _, spec := range specs {
+		path, caps, ok := strings.Cut(spec, "=")
+		path = strings.Trim(strings.TrimSpace(path), "/")
+		if !ok || path == "" || strings.TrimSpace(caps) == "" {
+			return nil, nil, fmt.Errorf("invalid capabilities specification: '%s'", spec)
+		}
+		if _, found := capabilities[path]; !found {
+			paths = append(paths, path)
+		}
+		for _, capability := range strings.Split(caps, ",") {
+			if capability = strings.TrimSpace(capability); capability != "" {
+				capabilities[path] = append(capabilities[path], capability)
+			}
+		}
+	}
+	return capabilities, paths, nil
+}
+
+// assertions returns the token properties selected by the command-line flags.
+func (c *TokenLookupCommand) assertions() (*tokenAssertions, error) {
+	a := &tokenAssertions{minUses: c.MinUses}
+
+	for _, policy := range strings.Split(c.Policies, ",") {
+		if policy = strings.TrimSpace(policy); policy != "" {
+			a.policies = append(a.policies, policy)
+		}
+	}
+
+	var err error
+	if a.orphan, err = parseOptionalBool("orphan", c.Orphan); err != nil {
+		return nil, err
+	}
+	if a.renewable, err = parseOptionalBool("renewable", c.Renewable); err != nil {
+		return nil, err
+	}
+	if a.periodic, err = parseOptionalBool("periodic", c.Periodic); err != nil {
+		return nil, err
+	}
+	if a.capabilities, a.paths, err = parseCapabilities(c.Capabilities); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// empty reports whether no token property is checked.
+func (a *tokenAssertions) empty() bool {
+	return len(a.policies) == 0 && a.orphan == nil && a.renewable == nil &&
+		a.periodic == nil && a.minUses == 0 && len(a.paths) == 0
+}
+
+// checkBool returns a violation message if the token property does not
+// have the expected value.
+func checkBool(property string, expected *bool, actual bool) []string {
+	if expected == nil || *expected == actual {
+		return nil
+	}
+	if *expected {
+		return []string{fmt.Sprintf("the token is not %s", property)}
+	}
+	return []string{fmt.Sprintf("the token is %s", property)}
+}
+
+// check returns the violations of the assertions by the given token.
+// The capabilities are read from sys/capabilities-self, or from
+// sys/capabilities-accessor when an accessor is given.
+func (a *tokenAssertions) check(client *api.Client, token tokenInfo, accessor string) []string {
+	var violations []string
+
+	active := append(append([]string{}, token.Policies...), token.IdentityPolicies...)
+	if missing, _, _ := checkPolicies(active, a.policies, "exact"); len(missing) > 0 {
+		violations = append(violations,
+			fmt.Sprintf("missing policies: %s", strings.Join(missing, ", ")))
+	}
+
+	violations = append(violations, checkBool("orphan", a.orphan, token.Orphan)...)
+	violations = append(violations, checkBool("renewable", a.renewable, token.Renewable)...)
+	violations = append(violations, checkBool("periodic", a.periodic, token.Period > 0)...)
+
+	if a.minUses > 0 && token.NumUses > 0 && token.NumUses < a.minUses {
+		violations = append(violations, fmt.Sprintf("the token has %d uses left (expected at least %d)",
+			token.NumUses, a.minUses))
+	}
+
+	for _, path := range a.paths {
+		var capabilities []string
+		var err error
+		if accessor == "" {
+			capabilities, err = client.Sys().CapabilitiesSelf(path)
+		} else {
+			capabilities, err = client.Sys().CapabilitiesAccessor(accessor, path)
+		}
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("cannot get the capabilities on %s: %s",
+				path, strings.TrimSpace(err.Error())))
+			continue
+		}
+		if contains(capabilities, "root") {
+			continue
+		}
+
+		var missing []string
+		for _, capability := range a.capabilities[path] {
+			if !contains(capabilities, capability) {
+				missing = append(missing, capability)
+			}
+		}
+		if len(missing) > 0 {
+			violations = append(violations, fmt.Sprintf("missing capabilities %s on %s (has: %s)",
+				strings.Join(missing, ","), path, strings.Join(capabilities, ",")))
+		}
+	}
+
+	return violations
+}
+
+// lastRenewal returns the time of the last renewal of the token, or its
+// creation time if it has never been renewed.
+func (t tokenInfo) lastRenewal() time.Time {
+	if t.LastRenewalTime > 0 {
+		return time.Unix(t.LastRenewalTime, 0)
+	}
+	return time.Unix(t.CreationTime, 0)
+}
+
+// expiration returns the time the token expires, or the zero time if the
+// token never expires. A periodic token expires one period after its last
+// renewal, or at its expire time if earlier (explicit max TTL).
+func (t tokenInfo) expiration() (time.Time, error) {
+	var expires time.Time
+	if t.ExpireTime != "" {
+		var err error
+		if expires, err = time.Parse(time.RFC3339Nano, t.ExpireTime); err != nil {
+			return time.Time{}, fmt.Errorf("invalid expire time: %s", t.ExpireTime)
+		}
+	}
+
+	if t.Period > 0 {
+		renewal := t.lastRenewal().Add(time.Duration(t.Period) * time.Second)
+		if expires.IsZero() || renewal.Before(expires) {
+			return renewal, nil
+		}
+	}
+	return expires, nil
+}
+
+// lookupToken returns the information about the current token, or about the
+// token associated to the given accessor.
+func lookupToken(client *api.Client, accessor string) (tokenInfo, error) {
+	var token tokenInfo
+	var secret *api.Secret
+	var err error
+
+	if accessor == "" {
+		secret, err = client.Auth().Token().LookupSelf()
+	} else {
+		secret, err = client.Auth().Token().LookupAccessor(accessor)
+	}
+	if err != nil {
+		return token, err
+	}
+	if secret == nil || secret.Data == nil {
+		return token, fmt.Errorf("cannot get the data of the Vault token")
+	}
+
+	if err = decodeData(secret.Data, &token); err != nil {
+		return token, err
+	}
+	token.expires, err = token.expiration()
+	return token, err
+}
+
+// renewToken renews the current token and returns its new TTL.
+// An error is returned when the renewal is refused or when the new TTL has
+// been capped by the max TTL of the token.
+func renewToken(client *api.Client, token tokenInfo) (time.Duration, error) {
+	if !token.Renewable {
+		return 0, fmt.Errorf("the token is not renewable")
+	}
+
+	secret, err := client.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return 0, fmt.Errorf("the token renewal has been refused: %s", strings.TrimSpace(err.Error()))
+	}
+	if secret == nil || secret.Auth == nil {
+		return 0, fmt.Errorf("the token renewal returned no data")
+	}
+
+	ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+	for _, warning := range secret.Warnings {
+		if strings.Contains(warning, "capped") {
+			return ttl, fmt.Errorf("the token renewal has been capped by the max TTL: %s", warning)
+		}
+	}
+
+	expected := time.Duration(token.CreationTTL) * time.Second
+	if token.Period > 0 {
+		expected = time.Duration(token.Period) * time.Second
+	}
+	if ttl < expected {
+		return ttl, fmt.Errorf("the token renewal has been capped by the max TTL (TTL %s instead of %s)",
+			ttl, expected)
+	}
+
+	return ttl, nil
+}
+
+// tokenLookupOutcomes are the outcomes of the `token-lookup` command.
+var tokenLookupOutcomes = []checkOutcome{
+	{"valid", StateOk, "the token does not expire within the thresholds"},
+	{"non-expiring", StateOk, "the token never expires (for instance a root token)"},
+	{"expiry-warning", StateWarning, "the token expires within -warning"},
+	{"expiry-critical", StateCritical, "the token expires within -critical"},
+	{"period-warning", StateWarning, "the periodic token reached -period-warning"},
+	{"period-critical", StateCritical, "the periodic token reached -period-critical"},
+	{"expired", StateCritical, "the token has expired"},
+	{"lookup-failed", StateCritical, "a token accessor cannot be looked up"},
+	{"assertion-failed", StateCritical, "a token assertion failed"},
+	{"renewal-failed", StateCritical, "the token renewal has been refused or capped by the max TTL"},
+}
+
+// tokenThresholds holds the thresholds the token expiration is checked against.
+type tokenThresholds struct {
+	warning        time.Duration
+	critical       time.Duration
+	periodWarning  int
+	periodCritical int
+}
+
+// outcome returns the outcome of the check of the token expiration time.
+// The periodic tokens are checked against the percentage of their period
+// elapsed since their last renewal, because they are expected to be
+// renewed well before the period expires.
+func (th tokenThresholds) outcome(token tokenInfo) string {
+	if token.expires.IsZero() {
+		return "non-expiring"
+	}
+
+	left := time.Until(token.expires)
+	if left <= 0 {
+		return "expired"
+	}
+
+	if token.Period > 0 {
+		period := time.Duration(token.Period) * time.Second
+		elapsed := int(100 * (period - left) / period)
+		switch {
+		case elapsed >= th.periodCritical:
+			return "period-critical"
+		case elapsed >= th.periodWarning:
+			return "period-warning"
+		}
+		return "valid"
+	}
+
+	switch {
+	case left < th.critical:
+		return "expiry-critical"
+	case left < th.warning:
+		return "expiry-warning"
+	}
+	return "valid"
+}
+
+// String returns a description of the token.
+func (t tokenInfo) String() string {
+	name := t.DisplayName
+	if name == "" {
+		name = "-"
+	}
+	return fmt.Sprintf("%s (display name: %s, policies: %s, creator: %s)",
+		t.Accessor, name, strings.Join(t.Policies, ","), t.creator)
 }
 
 // Synopsis returns a short synopsis of the `token-lookup` command.
@@ -61,8 +547,22 @@ Usage: hashicorp-vault-monitor token-lookup [options]
     -token-accessor=<string>
        The token accessor to lookup at (instead of the token itself).
 
-    -output=<string>
-       Specify an output format. Can be 'default' or 'nagios'.
+%s
+
+    -format=<string>
+       Go text/template building the output message in place of the default
+       one. The template is evaluated against the default message .Message,
+       the label of the state .State and the fields .Accessor, .DisplayName,
+       .Policies, .Path, .EntityID, .Renewable, .Orphan, .Periodic, .Period,
+       .Expires, .ExpireTime, .Remaining and .RemainingSeconds or, with the
+       '-all-accessors' and '-accessors-file' flags, .Checked, .NonExpiring
+       and .Problems (the number of tokens checked, never expiring and
+       expiring within the thresholds). The function 'join' joins a list of
+       strings:
+
+         -format='{{.DisplayName}} ({{join .Policies ","}}) expires in {{.Remaining}}'
+
+%s
 
     -warning=<string>
        Warning threshold in days (default: %s).
@@ -70,32 +570,88 @@ Usage: hashicorp-vault-monitor token-lookup [options]
     -critical=<string>
        Critical threshold in days (default: %s).
 
-  The exit code reflects the token expiration time:
+    -period-warning=<int>
+       Warning threshold for the periodic tokens, as the percentage of the
+       period elapsed since their last renewal (default: %d).
+
+    -period-critical=<int>
+       Critical threshold for the periodic tokens, as the percentage of the
+       period elapsed since their last renewal (default: %d).
+
+    -renew-below=<duration>
+       Renew the token when it expires in less than the given duration (for
+       instance '96h'). The TTL of the token before and after the renewal is
+       reported. A renewal refused by Vault or capped by the max TTL of the
+       token raises a critical state (outcome renewal-failed). Only the token
+       itself can be renewed, so this flag cannot be used with
+       -token-accessor.
+
+    -all-accessors
+       Lookup all the token accessors listed by the auth/token/accessors
+       endpoint (this requires the sudo capability) and report the tokens
+       expiring within the thresholds.
+
+    -accessors-file=<string>
+       Lookup the token accessors listed in the given file, one per line.
+       Empty lines and lines starting with a '#' are ignored.
+
+    -concurrency=<int>
+       Number of concurrent lookups of the token accessors (default: %d).
+
+  The following flags assert that the token (or the token associated to the
+  given accessor) has the expected properties. Each violation is reported
+  and raises a critical state.
+
+    -policies=<string>
+       Comma separated list of the policies (token or identity policies) the
+       token must carry.
+
+    -orphan=<bool>
+       Whether the token must ('true') or must not ('false') be orphan.
+
+    -renewable=<bool>
+       Whether the token must ('true') or must not ('false') be renewable.
+
+    -periodic=<bool>
+       Whether the token must ('true') or must not ('false') be periodic.
+
+    -min-uses=<int>
+       Minimum number of uses left, for the tokens created with a limited
+       number of uses.
+
+    -capability=<PATH=CAP1,CAP2>
+       The capabilities the token must have on the given path, as returned
+       by the sys/capabilities-self (or sys/capabilities-accessor) endpoint.
+       This flag can be repeated.
+
+  In the accessors sweep mode the display name, the policies and the creator
+  (the name of the identity entity or the path of the authentication method)
+  of each token expiring within the thresholds are reported.
+
+  By default, the exit code reflects the token expiration time:
 
       - %d - the token is usable
       - %d - the token will expire in less than the warning threshold
-      - %d - the token will expire in less than the critical threshold
+      - %d - the token will expire in less than the critical threshold,
+            a token accessor cannot be looked up, or an assertion failed
       - %d - an error occurred
 
   For a full list of examples, please see the online documentation.
 `
 	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("non-expiring=critical,expiry-warning=critical", tokenLookupOutcomes),
 		DefaultWarningTokenExpiration,
 		DefaultCriticalTokenExpiration,
+		DefaultPeriodWarning,
+		DefaultPeriodCritical,
+		DefaultTokenLookupConcurrency,
 		StateOk, StateWarning, StateCritical, StateUndefined)
 }
 
 // GetThresholds parses the warning and critical thresholds and return their corresponding Duration values
 func (c *TokenLookupCommand) GetThresholds() (time.Duration, time.Duration, error) {
-	warningThreshold, err := time.ParseDuration(c.WarningThreshold)
-	if err != nil {
-		return 0, 0, err
-	}
-	criticalThreshold, err := time.ParseDuration(c.CriticalThreshold)
-	if err != nil {
-		return 0, 0, err
-	}
-	return warningThreshold, criticalThreshold, nil
+	return parseDurationThresholds(c.WarningThreshold, c.CriticalThreshold)
 }
 
 // Run executes the `token-lookup` command with the given CLI instance and command-line arguments.
@@ -105,13 +661,33 @@ func (c *TokenLookupCommand) Run(args []string) int {
 	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
 	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
 	cmdFlags.StringVar(&c.TokenAccessor, "token-accessor", tokenAccessorDefault, tokenAccessorDescr)
-	cmdFlags.StringVar(&c.OutputFormat, "output", "default", outputFormatDescr)
+	c.addOutputFlags(cmdFlags)
+	cmdFlags.StringVar(&c.MessageTemplate, "format", "", messageTemplateDescr)
+	c.addStateFlag(cmdFlags, tokenLookupOutcomes)
 	cmdFlags.StringVar(&c.WarningThreshold, "warning",
 		DefaultWarningTokenExpiration,
 		fmt.Sprintf(warningDescr, DefaultWarningTokenExpiration))
 	cmdFlags.StringVar(&c.CriticalThreshold, "critical",
 		DefaultCriticalTokenExpiration,
 		fmt.Sprintf(criticalDescr, DefaultCriticalTokenExpiration))
+	cmdFlags.BoolVar(&c.AllAccessors, "all-accessors", false, tokenAllAccessorsDescr)
+	cmdFlags.StringVar(&c.AccessorsFile, "accessors-file", "", tokenAccessorsFileDescr)
+	cmdFlags.IntVar(&c.Concurrency, "concurrency",
+		DefaultTokenLookupConcurrency,
+		fmt.Sprintf(tokenConcurrencyDescr, DefaultTokenLookupConcurrency))
+	cmdFlags.IntVar(&c.PeriodWarning, "period-warning",
+		DefaultPeriodWarning,
+		fmt.Sprintf(tokenPeriodWarningDescr, DefaultPeriodWarning))
+	cmdFlags.IntVar(&c.PeriodCritical, "period-critical",
+		DefaultPeriodCritical,
+		fmt.Sprintf(tokenPeriodCriticalDescr, DefaultPeriodCritical))
+	cmdFlags.StringVar(&c.RenewBelow, "renew-below", "", tokenRenewBelowDescr)
+	cmdFlags.StringVar(&c.Policies, "policies", "", tokenPoliciesDescr)
+	cmdFlags.StringVar(&c.Orphan, "orphan", "", tokenOrphanDescr)
+	cmdFlags.StringVar(&c.Renewable, "renewable", "", tokenRenewableDescr)
+	cmdFlags.StringVar(&c.Periodic, "periodic", "", tokenPeriodicDescr)
+	cmdFlags.IntVar(&c.MinUses, "min-uses", 0, tokenMinUsesDescr)
+	cmdFlags.Var(&c.Capabilities, "capability", tokenCapabilitiesDescr)
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(err.Error())
@@ -135,72 +711,249 @@ func (c *TokenLookupCommand) Run(args []string) int {
 		out.Undefined(err.Error())
 		return StateUndefined
 	}
-
-	client, err := c.Client()
-	if err != nil {
-		out.Undefined(err.Error())
+	if c.PeriodWarning < 0 || c.PeriodCritical < c.PeriodWarning || c.PeriodCritical > 100 {
+		out.Undefined("The periodic tokens thresholds must be percentages, " +
+			"with the critical one greater than or equal to the warning one")
 		return StateUndefined
 	}
 
-	ta := client.Auth().Token()
+	var renewBelow time.Duration
+	if c.RenewBelow != "" {
+		if renewBelow, err = time.ParseDuration(c.RenewBelow); err != nil {
+			out.Undefined(err.Error())
+			return StateUndefined
+		}
+		if c.TokenAccessor != "" || c.AllAccessors || c.AccessorsFile != "" {
+			out.Undefined("The '-renew-below' flag can only be used to renew the token itself")
+			return StateUndefined
+		}
+	}
 
-	var secret *api.Secret
-	if c.TokenAccessor == "" {
-		secret, err = ta.LookupSelf()
-	} else {
-		secret, err = ta.LookupAccessor(c.TokenAccessor)
+	thresholds := tokenThresholds{
+		warning:        warningThreshold,
+		critical:       criticalThreshold,
+		periodWarning:  c.PeriodWarning,
+		periodCritical: c.PeriodCritical,
+	}
+
+	sweep := c.AllAccessors || c.AccessorsFile != ""
+	if sweep && c.TokenAccessor != "" {
+		out.Undefined("The '-token-accessor' flag cannot be used with '-all-accessors' or '-accessors-file'")
+		return StateUndefined
+	}
+	if c.Concurrency < 1 {
+		out.Undefined("The concurrency must be at least 1")
+		return StateUndefined
 	}
 
+	assertions, err := c.assertions()
 	if err != nil {
 		out.Undefined(err.Error())
 		return StateUndefined
 	}
-
-	if secret.Data == nil || secret.Data["expire_time"] == nil {
-		out.Undefined("Cannot get the expire time of the Vault token")
+	if sweep && !assertions.empty() {
+		out.Undefined("The token assertions cannot be used with '-all-accessors' or '-accessors-file'")
 		return StateUndefined
 	}
 
-	expireTimeRaw := secret.Data["expire_time"]
-	expireTimeStr, ok := expireTimeRaw.(string)
-	if !ok {
-		out.Undefined("Could not convert expire_time to a string")
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
 		return StateUndefined
 	}
 
-	t, _ := time.Parse(time.RFC3339Nano, expireTimeStr)
-	delta := time.Until(t)
+	if sweep {
+		return c.runSweep(out, client, thresholds)
+	}
 
-	retCode := StateOk
+	token, err := lookupToken(client, c.TokenAccessor)
+	if err != nil {
+		return c.report(out, "error", "%s", err)
+	}
+	violations := assertions.check(client, token, c.TokenAccessor)
+
+	var renewErr error
+	if before := time.Until(token.expires); !token.expires.IsZero() &&
+		before > 0 && before < renewBelow {
+		var after time.Duration
+		after, renewErr = renewToken(client, token)
+		if after > 0 {
+			out.AddDetail(StateOk, "the token has been renewed: TTL %s before the renewal, %s after",
+				before.Truncate(time.Second), after)
+			if token, err = lookupToken(client, ""); err != nil {
+				return c.report(out, "error", "%s", err)
+			}
+		}
+	}
+
+	delta := time.Until(token.expires)
+
+	if !token.expires.IsZero() {
+		ttl := PerfData{Label: "ttl", Value: delta.Truncate(time.Second).Seconds(), UOM: "s", Min: "0"}
+		if token.Period == 0 {
+			ttl.Warning = fmt.Sprintf("%d:", int64(thresholds.warning.Seconds()))
+			ttl.Critical = fmt.Sprintf("%d:", int64(thresholds.critical.Seconds()))
+		}
+		out.AddPerfData(ttl)
+	}
 
-	if delta > 0 {
-		var pluginMessage, renewable string
+	var left string
+	var leftSeconds int64
+	if !token.expires.IsZero() && delta > 0 {
+		d, _ := durafmt.ParseString(delta.Truncate(time.Second).String())
+		left, leftSeconds = d.String(), int64(delta.Seconds())
+	}
+
+	out.SetData(map[string]interface{}{
+		"Accessor":         token.Accessor,
+		"DisplayName":      token.DisplayName,
+		"Policies":         token.Policies,
+		"Path":             token.Path,
+		"EntityID":         token.EntityID,
+		"Renewable":        token.Renewable,
+		"Orphan":           token.Orphan,
+		"Periodic":         token.Period > 0,
+		"Period":           time.Duration(token.Period) * time.Second,
+		"Expires":          !token.expires.IsZero(),
+		"ExpireTime":       token.expires,
+		"Remaining":        left,
+		"RemainingSeconds": leftSeconds,
+	})
+
+	retCode := c.state(thresholds.outcome(token))
+	var pluginMessage string
+
+	switch {
+	case token.expires.IsZero():
+		kind := ""
+		if contains(token.Policies, "root") {
+			kind = "root "
+		}
+		pluginMessage = fmt.Sprintf("This %stoken never expires", kind)
+
+	case delta > 0:
+		var renewable string
 
-		if tokenIsRenewable, _ := secret.TokenIsRenewable(); tokenIsRenewable {
+		if token.Renewable {
 			renewable = "(renewable) "
 		}
 
-		left, _ := durafmt.ParseString(
-			delta.Truncate(time.Second).String())
-		pluginMessage = fmt.Sprintf("This %stoken will expire on %s (%s left)",
-			renewable,
-			t.Format(time.RFC1123),
-			left)
-
-		if delta < criticalThreshold {
-			out.Critical(pluginMessage)
-			retCode = StateCritical
-		} else if delta < warningThreshold {
-			out.Warning(pluginMessage)
-			retCode = StateWarning
+		if token.Period > 0 {
+			pluginMessage = fmt.Sprintf("This %speriodic token was last renewed on %s "+
+				"and will expire on %s if not renewed (%s left, period %s)",
+				renewable,
+				token.lastRenewal().UTC().Format(time.RFC1123),
+				token.expires.UTC().Format(time.RFC1123),
+				left,
+				time.Duration(token.Period)*time.Second)
 		} else {
-			out.Output(pluginMessage)
+			pluginMessage = fmt.Sprintf("This %stoken will expire on %s (%s left)",
+				renewable,
+				token.expires.Format(time.RFC1123),
+				left)
 		}
 
-	} else {
-		out.Critical("The token has expired!")
-		retCode = StateCritical
+	default:
+		pluginMessage = "The token has expired!"
+	}
+
+	for _, violation := range violations {
+		out.AddDetail(c.state("assertion-failed"), "%s", violation)
+		retCode = WorstState(retCode, c.state("assertion-failed"))
+	}
+	if renewErr != nil {
+		out.AddDetail(c.state("renewal-failed"), "%s", renewErr)
+		retCode = WorstState(retCode, c.state("renewal-failed"))
+	}
+
+	out.State(retCode)("%s", pluginMessage)
+	return retCode
+}
+
+// runSweep looks up all the token accessors, or the ones listed in the
+// accessors file, and reports the tokens expiring within the thresholds.
+func (c *TokenLookupCommand) runSweep(out *Outputter, client *api.Client,
+	thresholds tokenThresholds) int {
+	var accessors []string
+	if c.AccessorsFile != "" {
+		var err error
+		if accessors, err = readAccessorsFile(c.AccessorsFile); err != nil {
+			return c.report(out, "error", "error reading the token accessors: %s", err)
+		}
+	}
+	if c.AllAccessors {
+		listed, err := listAccessors(client)
+		if err != nil {
+			return c.report(out, "error", "error listing the token accessors: %s", err)
+		}
+		seen := make(map[string]struct{}, len(accessors)+len(listed))
+		for _, accessor := range accessors {
+			seen[accessor] = struct{}{}
+		}
+		for _, accessor := range listed {
+			if _, ok := seen[accessor]; !ok {
+				seen[accessor] = struct{}{}
+				accessors = append(accessors, accessor)
+			}
+		}
+	}
+	if len(accessors) == 0 {
+		out.Undefined("No token accessors to lookup")
+		return StateUndefined
+	}
+
+	tokens := lookupAccessors(client, accessors, c.Concurrency)
+
+	var expiring []tokenInfo
+	problems := 0
+	nonExpiring := 0
+	retCode := StateOk
+
+	report := func(code int, format string, a ...interface{}) {
+		out.AddDetail(code, format, a...)
+		retCode = WorstState(retCode, code)
+		problems++
+	}
+
+	for _, token := range tokens {
+		switch {
+		case token.err != nil:
+			report(c.state("lookup-failed"), "%s: lookup failed (the token may have expired): %s",
+				token.Accessor, strings.TrimSpace(token.err.Error()))
+		case token.expires.IsZero():
+			nonExpiring++
+			if state := c.state(thresholds.outcome(token)); state != StateOk {
+				report(state, "%s never expires", token)
+			}
+		default:
+			if outcome := thresholds.outcome(token); outcome != "valid" && c.state(outcome) != StateOk {
+				expiring = append(expiring, token)
+			}
+		}
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].expires.Before(expiring[j].expires)
+	})
+	for _, token := range expiring {
+		left, _ := durafmt.ParseString(
+			time.Until(token.expires).Truncate(time.Second).String())
+		report(c.state(thresholds.outcome(token)), "%s expires on %s (%s left)",
+			token, token.expires.Format(time.RFC1123), left)
+	}
+
+	out.SetData(map[string]interface{}{
+		"Checked":     len(tokens),
+		"NonExpiring": nonExpiring,
+		"Problems":    problems,
+	})
+
+	summary := fmt.Sprintf("%d tokens checked, %d non-expiring", len(tokens), nonExpiring)
+	if problems == 0 {
+		return c.report(out, "valid", "None of the tokens expires within %s (%s)", thresholds.warning, summary)
 	}
 
+	out.State(retCode)("%d tokens expire within the thresholds or cannot be looked up (%s)",
+		problems, summary)
 	return retCode
 }
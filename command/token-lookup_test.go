@@ -20,10 +20,15 @@
 package command
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 )
 
@@ -94,6 +99,60 @@ func TestTokenLookupCommand_GetThresholds(t *testing.T) {
 	})
 }
 
+func TestTokenInfo_Expiration(t *testing.T) {
+	created := int64(1760000000)
+	at := func(offset time.Duration) time.Time {
+		return time.Unix(created, 0).Add(offset).UTC()
+	}
+
+	cases := []struct {
+		name     string
+		token    tokenInfo
+		shouldbe time.Time
+		fail     bool
+	}{
+		{"non_expiring", tokenInfo{CreationTime: created}, time.Time{}, false},
+		{
+			"expire_time",
+			tokenInfo{CreationTime: created, ExpireTime: at(time.Hour).Format(time.RFC3339Nano)},
+			at(time.Hour),
+			false,
+		},
+		{"periodic", tokenInfo{CreationTime: created, Period: 3600}, at(time.Hour), false},
+		{
+			"periodic_renewed",
+			tokenInfo{CreationTime: created, LastRenewalTime: created + 600, Period: 3600},
+			at(70 * time.Minute),
+			false,
+		},
+		{
+			"periodic_explicit_max_ttl",
+			tokenInfo{CreationTime: created, Period: 3600, ExpireTime: at(30 * time.Minute).Format(time.RFC3339Nano)},
+			at(30 * time.Minute),
+			false,
+		},
+		{
+			"periodic_later_expire_time",
+			tokenInfo{CreationTime: created, Period: 3600, ExpireTime: at(2 * time.Hour).Format(time.RFC3339Nano)},
+			at(time.Hour),
+			false,
+		},
+		{"invalid_expire_time", tokenInfo{CreationTime: created, ExpireTime: "tomorrow"}, time.Time{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expires, err := tc.token.expiration()
+			if (err != nil) != tc.fail {
+				t.Fatalf("unexpected error value: %v", err)
+			}
+			if !expires.Equal(tc.shouldbe) {
+				t.Errorf("expected %s to be %s", expires, tc.shouldbe)
+			}
+		})
+	}
+}
+
 func TestTokenLookupCommand_Run(t *testing.T) {
 	t.Parallel()
 
@@ -115,14 +174,36 @@ func TestTokenLookupCommand_Run(t *testing.T) {
 			"Too many arguments",
 			StateUndefined,
 		},
-		//{
-		//	"token_expiration_ok",
-		//	[]string{},
-		//	// FIXME: we get "Cannot get the expire time of the Vault token"
-		//	// because s.Data["expire_time"] == nil
-		//	"FIXME",
-		//	StateUndefined,
-		//},
+		{
+			"root_token_never_expires",
+			[]string{},
+			"This root token never expires",
+			StateOk,
+		},
+		{
+			"root_token_custom_format",
+			[]string{"-format", "{{.DisplayName}} token ({{join .Policies \",\"}}), expires: {{.Expires}}"},
+			"root token (root), expires: false",
+			StateOk,
+		},
+		{
+			"root_token_non_expiring_state",
+			[]string{"-state", "non-expiring=critical"},
+			"This root token never expires",
+			StateCritical,
+		},
+		{
+			"invalid_non_expiring_state",
+			[]string{"-state", "non-expiring=bad"},
+			"invalid value \"non-expiring=bad\" for flag -state",
+			StateUndefined,
+		},
+		{
+			"invalid_period_thresholds",
+			[]string{"-period-warning", "80", "-period-critical", "60"},
+			"The periodic tokens thresholds must be percentages",
+			StateUndefined,
+		},
 		{
 			"nagios_too_many_args",
 			[]string{"-output", "nagios", "arg1"},
@@ -181,3 +262,493 @@ func TestTokenLookupCommand_Run(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenLookupCommand_Sweep(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		ttls []string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"all_accessors_ok",
+			[]string{"500h"},
+			[]string{"-all-accessors"},
+			"None of the tokens expires within 168h0m0s (2 tokens checked, 1 non-expiring)",
+			StateOk,
+		},
+		{
+			"all_accessors_valid_state",
+			[]string{"500h"},
+			[]string{"-all-accessors", "-state", "valid=warning"},
+			"None of the tokens expires within 168h0m0s (2 tokens checked, 1 non-expiring)",
+			StateWarning,
+		},
+		{
+			"all_accessors_warning",
+			[]string{"500h", "100h"},
+			[]string{"-all-accessors", "-concurrency", "1"},
+			"1 tokens expire within the thresholds or cannot be looked up (3 tokens checked, 1 non-expiring)",
+			StateWarning,
+		},
+		{
+			"all_accessors_critical",
+			[]string{"100h", "1h"},
+			[]string{"-all-accessors"},
+			"display name: token-sweep-1, policies: default, creator: auth/token/create",
+			StateCritical,
+		},
+		{
+			"accessors_file",
+			[]string{"1h"},
+			[]string{"-accessors-file"},
+			"1 tokens expire within the thresholds or cannot be looked up (1 tokens checked, 0 non-expiring)",
+			StateCritical,
+		},
+		{
+			"invalid_accessor",
+			[]string{},
+			[]string{"-accessors-file"},
+			"[CRITICAL] invalid-accessor: lookup failed",
+			StateCritical,
+		},
+		{
+			"token_accessor_conflict",
+			[]string{},
+			[]string{"-all-accessors", "-token-accessor", "foo"},
+			"cannot be used with",
+			StateUndefined,
+		},
+		{
+			"missing_accessors_file",
+			[]string{},
+			[]string{"-accessors-file", "/nonexistent"},
+			"error reading the token accessors",
+			StateUndefined,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, _, closer := testVaultServerUnseal(t)
+			defer closer()
+
+			var accessors []string
+			for i, ttl := range tc.ttls {
+				secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+					DisplayName: fmt.Sprintf("sweep-%d", i),
+					Policies:    []string{"default"},
+					TTL:         ttl,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				accessors = append(accessors, secret.Auth.Accessor)
+			}
+
+			args := tc.args
+			if len(args) == 1 && args[0] == "-accessors-file" {
+				if len(accessors) == 0 {
+					accessors = []string{"invalid-accessor"}
+				}
+				file := filepath.Join(t.TempDir(), "accessors")
+				content := "# token accessors\n\n" + strings.Join(accessors, "\n") + "\n"
+				if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, file)
+			}
+
+			ui, cmd := testTokenLookupCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestTokenLookupCommand_Assertions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		self bool
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"assertions_ok",
+			false,
+			[]string{"-policies", "default", "-orphan", "false", "-renewable", "true",
+				"-periodic", "false", "-min-uses", "2",
+				"-capability", "auth/token/lookup-self=read"},
+			"This (renewable) token will expire on",
+			StateOk,
+		},
+		{
+			"assertions_self_ok",
+			true,
+			[]string{"-policies", "default", "-capability", "auth/token/lookup-self=read"},
+			"This (renewable) token will expire on",
+			StateOk,
+		},
+		{
+			"missing_policies",
+			false,
+			[]string{"-policies", "default,app,ops"},
+			"[CRITICAL] missing policies: app, ops",
+			StateCritical,
+		},
+		{
+			"orphan",
+			false,
+			[]string{"-orphan", "true", "-renewable", "false"},
+			"[CRITICAL] the token is not orphan\n[CRITICAL] the token is renewable",
+			StateCritical,
+		},
+		{
+			"periodic",
+			false,
+			[]string{"-periodic", "true"},
+			"[CRITICAL] the token is not periodic",
+			StateCritical,
+		},
+		{
+			"min_uses",
+			false,
+			[]string{"-min-uses", "10"},
+			"[CRITICAL] the token has 3 uses left (expected at least 10)",
+			StateCritical,
+		},
+		{
+			"missing_capabilities",
+			true,
+			[]string{"-capability", "secret/foo=read,list"},
+			"[CRITICAL] missing capabilities read,list on secret/foo (has: deny)",
+			StateCritical,
+		},
+		{
+			"invalid_capabilities",
+			false,
+			[]string{"-capability", "secret/foo"},
+			"invalid capabilities specification: 'secret/foo'",
+			StateUndefined,
+		},
+		{
+			"invalid_bool",
+			false,
+			[]string{"-orphan", "maybe"},
+			"invalid value 'maybe' for the flag -orphan",
+			StateUndefined,
+		},
+		{
+			"sweep_conflict",
+			false,
+			[]string{"-all-accessors", "-policies", "default"},
+			"The token assertions cannot be used with",
+			StateUndefined,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, _, closer := testVaultServerUnseal(t)
+			defer closer()
+
+			secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+				Policies: []string{"default"},
+				TTL:      "500h",
+				NumUses:  3,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ui, cmd := testTokenLookupCommand(t)
+			cmd.client = client
+
+			args := tc.args
+			if tc.self {
+				if cmd.client, err = client.Clone(); err != nil {
+					t.Fatal(err)
+				}
+				cmd.client.SetToken(secret.Auth.ClientToken)
+			} else if !strings.HasPrefix(tc.name, "sweep") {
+				args = append([]string{"-token-accessor", secret.Auth.Accessor}, args...)
+			}
+
+			code := cmd.Run(args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestTokenLookupCommand_ZabbixTTL(t *testing.T) {
+	t.Parallel()
+
+	client, _, closer := testVaultServerUnseal(t)
+	defer closer()
+
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"default"},
+		TTL:      "500h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ui, cmd := testTokenLookupCommand(t)
+	cmd.client = client
+
+	code := cmd.Run([]string{"-output", "zabbix:ttl", "-token-accessor", secret.Auth.Accessor})
+	if code != StateOk {
+		t.Errorf("expected %d to be %d", code, StateOk)
+	}
+
+	// the TTL decreases while the test runs
+	output := strings.TrimSpace(ui.OutputWriter.String())
+	ttl, err := strconv.Atoi(output)
+	if err != nil {
+		t.Fatalf("expected a TTL, got %q", output)
+	}
+	if max := int((500 * time.Hour).Seconds()); ttl > max || ttl < max-60 {
+		t.Errorf("expected the TTL %d to be in [%d, %d]", ttl, max-60, max)
+	}
+}
+
+func TestTokenLookupCommand_Periodic(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		renewed time.Duration
+		expires time.Duration
+		args    []string
+		out     string
+		code    int
+	}{
+		{
+			"periodic_ok",
+			10 * time.Minute,
+			0,
+			[]string{},
+			"This (renewable) periodic token was last renewed on",
+			StateOk,
+		},
+		{
+			"periodic_warning",
+			40 * time.Minute,
+			0,
+			[]string{},
+			"(19 minutes ",
+			StateWarning,
+		},
+		{
+			"periodic_critical",
+			50 * time.Minute,
+			0,
+			[]string{},
+			"(9 minutes ",
+			StateCritical,
+		},
+		{
+			"periodic_thresholds",
+			50 * time.Minute,
+			0,
+			[]string{"-period-warning", "90", "-period-critical", "95"},
+			"will expire on",
+			StateOk,
+		},
+		{
+			"periodic_explicit_max_ttl",
+			10 * time.Minute,
+			5 * time.Minute,
+			[]string{},
+			"(4 minutes ",
+			StateCritical,
+		},
+		{
+			"periodic_expired",
+			2 * time.Hour,
+			0,
+			[]string{},
+			"The token has expired!",
+			StateCritical,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the expire_time of a periodic token is one period after its
+			// last renewal, unless capped by an explicit max TTL
+			renewed := time.Now().Add(-tc.renewed).Add(-30 * time.Second)
+			expires := renewed.Add(time.Hour)
+			if tc.expires > 0 {
+				expires = time.Now().Add(tc.expires).Add(-30 * time.Second)
+			}
+			client, closer := testVaultServerJSON(t, map[string]string{
+				"/v1/auth/token/lookup-self": fmt.Sprintf(`{"data": {
+					"accessor": "8609694a-cdbc-db9b-d345-e782dbb562ed",
+					"creation_time": %d,
+					"expire_time": "%s",
+					"last_renewal_time": %d,
+					"period": 3600,
+					"policies": ["default"],
+					"renewable": true}}`,
+					time.Now().Add(-24*time.Hour).Unix(),
+					expires.UTC().Format(time.RFC3339),
+					renewed.Unix()),
+			})
+			defer closer()
+
+			ui, cmd := testTokenLookupCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestTokenLookupCommand_Renew(t *testing.T) {
+	t.Parallel()
+
+	renewable, notRenewable := true, false
+
+	cases := []struct {
+		name    string
+		request *api.TokenCreateRequest
+		wait    time.Duration
+		args    []string
+		out     string
+		code    int
+	}{
+		{
+			"renewed",
+			&api.TokenCreateRequest{TTL: "1h", Renewable: &renewable},
+			0,
+			[]string{"-renew-below", "2h", "-warning", "30m", "-critical", "10m"},
+			"[OK] the token has been renewed: TTL 59m",
+			StateOk,
+		},
+		{
+			"not_below_threshold",
+			&api.TokenCreateRequest{TTL: "1h", Renewable: &renewable},
+			0,
+			[]string{"-renew-below", "30m", "-warning", "20m", "-critical", "10m"},
+			"This (renewable) token will expire on",
+			StateOk,
+		},
+		{
+			"capped",
+			&api.TokenCreateRequest{TTL: "1h", ExplicitMaxTTL: "1h", Renewable: &renewable},
+			// let the max TTL cap the renewal
+			2 * time.Second,
+			[]string{"-renew-below", "2h", "-warning", "30m", "-critical", "10m"},
+			"[CRITICAL] the token renewal has been capped by the max TTL",
+			StateCritical,
+		},
+		{
+			"capped_state",
+			&api.TokenCreateRequest{TTL: "1h", ExplicitMaxTTL: "1h", Renewable: &renewable},
+			2 * time.Second,
+			[]string{"-renew-below", "2h", "-warning", "30m", "-critical", "10m",
+				"-state", "renewal-failed=warning"},
+			"[WARNING] the token renewal has been capped by the max TTL",
+			StateWarning,
+		},
+		{
+			"capped_assertion_failed",
+			&api.TokenCreateRequest{TTL: "1h", ExplicitMaxTTL: "1h", Renewable: &renewable},
+			2 * time.Second,
+			[]string{"-renew-below", "2h", "-warning", "30m", "-critical", "10m",
+				"-state", "renewal-failed=ok", "-policies", "nosuchpolicy"},
+			"[CRITICAL] missing policies: nosuchpolicy",
+			StateCritical,
+		},
+		{
+			"not_renewable",
+			&api.TokenCreateRequest{TTL: "1h", Renewable: &notRenewable},
+			0,
+			[]string{"-renew-below", "2h", "-warning", "30m", "-critical", "10m"},
+			"[CRITICAL] the token is not renewable",
+			StateCritical,
+		},
+		{
+			"token_accessor",
+			&api.TokenCreateRequest{TTL: "1h"},
+			0,
+			[]string{"-renew-below", "2h", "-token-accessor", "foo"},
+			"can only be used to renew the token itself",
+			StateUndefined,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, _, closer := testVaultServerUnseal(t)
+			defer closer()
+
+			secret, err := client.Auth().Token().Create(tc.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			time.Sleep(tc.wait)
+
+			ui, cmd := testTokenLookupCommand(t)
+			if cmd.client, err = client.Clone(); err != nil {
+				t.Fatal(err)
+			}
+			cmd.client.SetToken(secret.Auth.ClientToken)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Default values for the `transit-roundtrip` command.
+const (
+	DefaultTransitMount    = "transit"
+	DefaultTransitWarning  = "500ms"
+	DefaultTransitCritical = "2s"
+)
+
+const (
+	transitMountDescr    = "Mount path of the transit secrets engine (default: %s)"
+	transitKeyDescr      = "Name of the transit key to check"
+	transitSignDescr     = "Also sign the nonce and verify the signature"
+	transitRewrapDescr   = "Also rewrap the ciphertext with the latest key version"
+	transitWarningDescr  = "Warning if the roundtrip takes longer than this time (default: %s)"
+	transitCriticalDescr = "Critical if the roundtrip takes longer than this time (default: %s)"
+)
+
+// transitRoundtripOutcomes are the outcomes of the `transit-roundtrip` command.
+var transitRoundtripOutcomes = []checkOutcome{
+	{"completed", StateOk, "the roundtrip completed within the thresholds"},
+	{"slow-warning", StateWarning, "the roundtrip took longer than -warning"},
+	{"slow-critical", StateCritical, "the roundtrip took longer than -critical"},
+	{"missing-key", StateCritical, "the transit key does not exist"},
+	{"failed", StateCritical, "an operation of the roundtrip failed"},
+}
+
+// TransitRoundtripCommand is a CLI Command that holds the attributes of the command `transit-roundtrip`.
+type TransitRoundtripCommand struct {
+	*BaseCommand
+	Mount             string
+	Key               string
+	Sign              bool
+	Rewrap            bool
+	WarningThreshold  string
+	CriticalThreshold string
+}
+
+// transitKeyInfo holds the information about a transit key.
+type transitKeyInfo struct {
+	Type               string `json:"type"`
+	SupportsEncryption bool   `json:"supports_encryption"`
+	SupportsSigning    bool   `json:"supports_signing"`
+	LatestVersion      int    `json:"latest_version"`
+}
+
+// transitWrite writes data to the transit endpoint op of the given key and
+// returns the value of the field of the response.
+func transitWrite(ctx context.Context, client *api.Client, mount, op, key string,
+	data map[string]interface{}, field string) (interface{}, error) {
+	secret, err := client.Logical().WriteWithContext(ctx,
+		fmt.Sprintf("%s/%s/%s", mount, op, key), data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data[field] == nil {
+		return nil, fmt.Errorf("no %s returned", field)
+	}
+	return secret.Data[field], nil
+}
+
+// transitEncrypt encrypts the plaintext with a transit key.
+func transitEncrypt(ctx context.Context, client *api.Client, mount, key string,
+	plaintext []byte) (string, error) {
+	ciphertext, err := transitWrite(ctx, client, mount, "encrypt", key,
+		map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString(plaintext)},
+		"ciphertext")
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %s", err)
+	}
+	s, ok := ciphertext.(string)
+	if !ok {
+		return "", errors.New("encryption failed: the ciphertext is not a string")
+	}
+	return s, nil
+}
+
+// transitDecrypt decrypts the ciphertext with a transit key.
+func transitDecrypt(ctx context.Context, client *api.Client, mount, key string,
+	ciphertext string) ([]byte, error) {
+	encoded, err := transitWrite(ctx, client, mount, "decrypt", key,
+		map[string]interface{}{"ciphertext": ciphertext}, "plaintext")
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %s", err)
+	}
+	s, ok := encoded.(string)
+	if !ok {
+		return nil, errors.New("decryption failed: the plaintext is not a string")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %s", err)
+	}
+	return plaintext, nil
+}
+
+// transitRoundtrip encrypts and decrypts the given plaintext with a transit
+// key and checks that the decrypted text matches the original one.
+func transitRoundtrip(ctx context.Context, client *api.Client, mount, key string,
+	plaintext []byte) error {
+	ciphertext, err := transitEncrypt(ctx, client, mount, key, plaintext)
+	if err != nil {
+		return err
+	}
+	decrypted, err := transitDecrypt(ctx, client, mount, key, ciphertext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		return errors.New("decryption failed: the plaintext does not match")
+	}
+	return nil
+}
+
+// transitSteps performs the transit operations supported by the key on a
+// random nonce and returns the time spent by each of them, in the order they
+// are performed.
+func (c *TransitRoundtripCommand) transitSteps(client *api.Client, key transitKeyInfo) ([]string,
+	map[string]time.Duration, error) {
+	ctx := context.Background()
+
+	nonce, err := randomNonce()
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext := []byte(nonce)
+
+	var steps []string
+	timings := make(map[string]time.Duration)
+	timed := func(step string, f func() error) error {
+		start := time.Now()
+		err := f()
+		steps = append(steps, step)
+		timings[step] = time.Since(start)
+		return err
+	}
+
+	if !key.SupportsEncryption {
+		err := c.signSteps(ctx, client, plaintext, timed)
+		return steps, timings, err
+	}
+
+	var ciphertext string
+	if err := timed("encrypt", func() (err error) {
+		ciphertext, err = transitEncrypt(ctx, client, c.Mount, c.Key, plaintext)
+		return err
+	}); err != nil {
+		return steps, timings, err
+	}
+
+	if c.Rewrap {
+		if err := timed("rewrap", func() error {
+			rewrapped, err := transitWrite(ctx, client, c.Mount, "rewrap", c.Key,
+				map[string]interface{}{"ciphertext": ciphertext}, "ciphertext")
+			if err != nil {
+				return fmt.Errorf("rewrap failed: %s", err)
+			}
+			s, ok := rewrapped.(string)
+			if !ok {
+				return errors.New("rewrap failed: the ciphertext is not a string")
+			}
+			ciphertext = s
+			return nil
+		}); err != nil {
+			return steps, timings, err
+		}
+	}
+
+	if err := timed("decrypt", func() error {
+		decrypted, err := transitDecrypt(ctx, client, c.Mount, c.Key, ciphertext)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			return errors.New("decryption failed: the plaintext does not match")
+		}
+		return nil
+	}); err != nil {
+		return steps, timings, err
+	}
+
+	if c.Sign {
+		err = c.signSteps(ctx, client, plaintext, timed)
+	}
+	return steps, timings, err
+}
+
+// signSteps signs the plaintext and verifies the signature.
+func (c *TransitRoundtripCommand) signSteps(ctx context.Context, client *api.Client,
+	plaintext []byte, timed func(string, func() error) error) error {
+	input := base64.StdEncoding.EncodeToString(plaintext)
+
+	var signature string
+	if err := timed("sign", func() error {
+		s, err := transitWrite(ctx, client, c.Mount, "sign", c.Key,
+			map[string]interface{}{"input": input}, "signature")
+		if err != nil {
+			return fmt.Errorf("signing failed: %s", err)
+		}
+		var ok bool
+		if signature, ok = s.(string); !ok {
+			return errors.New("signing failed: the signature is not a string")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return timed("verify", func() error {
+		valid, err := transitWrite(ctx, client, c.Mount, "verify", c.Key,
+			map[string]interface{}{"input": input, "signature": signature}, "valid")
+		if err != nil {
+			return fmt.Errorf("verification failed: %s", err)
+		}
+		if ok, _ := valid.(bool); !ok {
+			return errors.New("verification failed: the signature is not valid")
+		}
+		return nil
+	})
+}
+
+// Synopsis returns a short synopsis of the `transit-roundtrip` command.
+func (c *TransitRoundtripCommand) Synopsis() string {
+	return "Check a transit key with an encryption and decryption roundtrip"
+}
+
+// Help returns a long-form help text of the `transit-roundtrip` command.
+func (c *TransitRoundtripCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor transit-roundtrip [options] -key KEY
+
+  This command encrypts a random nonce with a transit key, decrypts it and
+  checks that the decrypted text matches the nonce. The nonce can also be
+  signed and its signature verified, and the ciphertext can be rewrapped
+  with the latest version of the key before being decrypted.
+
+    $ hashicorp-vault-monitor transit-roundtrip -key payments -sign
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -mount=<string>
+       Mount path of the transit secrets engine (default: %s).
+
+    -key=<string>
+       Name of the transit key to check.
+
+    -sign
+       Also sign the nonce and verify the signature. The key must support
+       signing (for instance an 'ed25519' or 'ecdsa-p256' key). The
+       encryption roundtrip is skipped for the keys that only support
+       signing.
+
+    -rewrap
+       Also rewrap the ciphertext with the latest version of the key.
+
+    -warning=<duration>
+       Warning if the roundtrip takes longer than this time (default: %s).
+
+    -critical=<duration>
+       Critical if the roundtrip takes longer than this time (default: %s).
+
+  The token must be allowed to read the key and to update the encrypt,
+  decrypt, and optionally the rewrap, sign and verify endpoints of the key.
+
+  By default, the exit code reflects the result of the roundtrip:
+
+      - %d - the roundtrip succeeded
+      - %d - the roundtrip took longer than the warning threshold
+      - %d - an operation failed, or the roundtrip took longer than the
+            critical threshold
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("slow-warning=ok,error=critical", transitRoundtripOutcomes),
+		DefaultTransitMount,
+		DefaultTransitWarning,
+		DefaultTransitCritical,
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `transit-roundtrip` command with the given CLI instance and command-line arguments.
+func (c *TransitRoundtripCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("transit-roundtrip", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, transitRoundtripOutcomes)
+	cmdFlags.StringVar(&c.Mount, "mount",
+		DefaultTransitMount,
+		fmt.Sprintf(transitMountDescr, DefaultTransitMount))
+	cmdFlags.StringVar(&c.Key, "key", "", transitKeyDescr)
+	cmdFlags.BoolVar(&c.Sign, "sign", false, transitSignDescr)
+	cmdFlags.BoolVar(&c.Rewrap, "rewrap", false, transitRewrapDescr)
+	cmdFlags.StringVar(&c.WarningThreshold, "warning",
+		DefaultTransitWarning,
+		fmt.Sprintf(transitWarningDescr, DefaultTransitWarning))
+	cmdFlags.StringVar(&c.CriticalThreshold, "critical",
+		DefaultTransitCritical,
+		fmt.Sprintf(transitCriticalDescr, DefaultTransitCritical))
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	if c.Key == "" {
+		out.Undefined("The transit key must be set with '-key'")
+		return StateUndefined
+	}
+	c.Mount = strings.Trim(c.Mount, "/")
+
+	warningThreshold, criticalThreshold, err := parseDurationThresholds(
+		c.WarningThreshold, c.CriticalThreshold)
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		out.Undefined(err.Error())
+		return StateUndefined
+	}
+
+	// the key is read first, because the encrypt endpoint creates the
+	// missing keys when the token is allowed to do so
+	secret, err := client.Logical().Read(fmt.Sprintf("%s/keys/%s", c.Mount, c.Key))
+	if err != nil {
+		return c.report(out, "error", "error reading the transit key %s: %s", c.Key, err)
+	}
+	if secret == nil {
+		return c.report(out, "missing-key", "no such transit key: %s/keys/%s", c.Mount, c.Key)
+	}
+
+	var key transitKeyInfo
+	if err := decodeData(secret.Data, &key); err != nil {
+		return c.report(out, "error", "error decoding the transit key %s: %s", c.Key, err)
+	}
+	if c.Sign && !key.SupportsSigning {
+		return c.report(out, "error", "the transit key %s (type: %s) does not support signing", c.Key, key.Type)
+	}
+	if !c.Sign && !key.SupportsEncryption {
+		return c.report(out, "error", "the transit key %s (type: %s) does not support encryption", c.Key, key.Type)
+	}
+
+	steps, timings, err := c.transitSteps(client, key)
+
+	var total time.Duration
+	var details []string
+	for _, step := range steps {
+		total += timings[step]
+		out.AddPerfData(PerfData{
+			Label: step, Value: milliseconds(timings[step]), UOM: "ms", Min: "0"})
+		details = append(details, fmt.Sprintf("%s %s", step, timings[step].Round(time.Microsecond)))
+	}
+	out.AddPerfData(PerfData{
+		Label:    "total",
+		Value:    milliseconds(total),
+		UOM:      "ms",
+		Warning:  fmt.Sprint(milliseconds(warningThreshold)),
+		Critical: fmt.Sprint(milliseconds(criticalThreshold)),
+		Min:      "0",
+	})
+
+	if err != nil {
+		return c.report(out, "failed", "transit key %s: %s", c.Key, err)
+	}
+
+	outcome := "completed"
+	switch {
+	case total > criticalThreshold:
+		outcome = "slow-critical"
+	case total > warningThreshold:
+		outcome = "slow-warning"
+	}
+
+	return c.report(out, outcome, "transit key %s (version %d) roundtrip took %s (%s)",
+		c.Key, key.LatestVersion, total.Round(time.Microsecond), strings.Join(details, ", "))
+}
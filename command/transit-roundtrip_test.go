@@ -0,0 +1,195 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testTransitRoundtripCommand(t *testing.T) (*cli.MockUi, *TransitRoundtripCommand) {
+	ui := cli.NewMockUi()
+	return ui, &TransitRoundtripCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestTransitRoundtripCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor transit-roundtrip [options] -key KEY",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"missing_key",
+			[]string{},
+			"The transit key must be set with '-key'",
+			StateUndefined,
+		},
+		{
+			"bad_threshold",
+			[]string{"-key", "aes", "-warning", "foo"},
+			"invalid duration",
+			StateUndefined,
+		},
+		{
+			"roundtrip",
+			[]string{"-key", "aes"},
+			"transit key aes (version 1) roundtrip took ",
+			StateOk,
+		},
+		{
+			"rewrap",
+			[]string{"-key", "aes", "-rewrap"},
+			", rewrap ",
+			StateOk,
+		},
+		{
+			"sign",
+			[]string{"-key", "signing", "-sign"},
+			"transit key signing (version 1) roundtrip took ",
+			StateOk,
+		},
+		{
+			"sign_unsupported",
+			[]string{"-key", "aes", "-sign"},
+			"the transit key aes (type: aes256-gcm96) does not support signing",
+			StateUndefined,
+		},
+		{
+			"no_such_key",
+			[]string{"-key", "nosuchkey"},
+			"no such transit key: transit/keys/nosuchkey",
+			StateCritical,
+		},
+		{
+			"slow_roundtrip",
+			[]string{"-key", "aes", "-warning", "0s", "-critical", "1h"},
+			"transit key aes (version 1) roundtrip took ",
+			StateWarning,
+		},
+		{
+			"nagios_perfdata",
+			[]string{"-output", "nagios", "-key", "aes", "-rewrap"},
+			";500;2000;0",
+			StateOk,
+		},
+	}
+
+	t.Run("transit", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, _, closer := testVaultServerUnseal(t)
+				defer closer()
+
+				if err := client.Sys().Mount("transit", &api.MountInput{
+					Type: "transit",
+				}); err != nil {
+					t.Fatal(err)
+				}
+				for key, keyType := range map[string]string{"aes": "aes256-gcm96", "signing": "ed25519"} {
+					if _, err := client.Logical().Write("transit/keys/"+key, map[string]interface{}{
+						"type": keyType,
+					}); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				ui, cmd := testTransitRoundtripCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("malformed_response", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerJSON(t, map[string]string{
+			"/v1/transit/keys/aes": `{"data": {"type": "aes256-gcm96",
+				"supports_encryption": true, "latest_version": 1}}`,
+			"/v1/transit/encrypt/aes": `{"data": {"ciphertext": 42}}`,
+		})
+		defer closer()
+
+		ui, cmd := testTransitRoundtripCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-key", "aes"})
+		if exp := StateCritical; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "transit key aes: encryption failed: the ciphertext is not a string"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testTransitRoundtripCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-key", "aes"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error reading the transit key aes: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}
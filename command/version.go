@@ -0,0 +1,335 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+const (
+	versionWarningDescr    = "Warning if the Vault version is lower than this one"
+	versionCriticalDescr   = "Critical if the Vault version is lower than this one"
+	versionVulnerableDescr = "File containing the list of the known vulnerable Vault versions"
+)
+
+// VersionCommand is a CLI Command that holds the attributes of the command `version`.
+type VersionCommand struct {
+	*BaseCommand
+	WarningVersion  string
+	CriticalVersion string
+	VulnerableFile  string
+	Nodes           string
+	Discover        bool
+}
+
+// versionOutcomes are the outcomes of the `version` command.
+var versionOutcomes = []checkOutcome{
+	{"up-to-date", StateOk, "the version is up to date"},
+	{"older-than-warning", StateWarning, "the version is lower than the -warning one"},
+	{"older-than-critical", StateCritical, "the version is lower than the -critical one"},
+	{"vulnerable", StateCritical, "the version is listed in the -vulnerable-file"},
+	{"version-mismatch", StateWarning, "the cluster nodes run different versions"},
+	{"node-unreachable", StateUndefined, "a cluster node is unreachable"},
+}
+
+// vulnerableVersions is an entry of the file of the known vulnerable versions.
+type vulnerableVersions struct {
+	constraints goversion.Constraints
+	advisory    string
+}
+
+// loadVulnerableVersions reads the file at path, containing one version
+// constraint per line (e.g. ">= 1.13.0, < 1.13.8") optionally followed by
+// a '#' and a description of the vulnerability. Empty lines and lines
+// starting with a '#' are ignored.
+func loadVulnerableVersions(path string) ([]vulnerableVersions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []vulnerableVersions
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var advisory string
+		if i := strings.Index(line, "#"); i >= 0 {
+			line, advisory = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+
+		constraints, err := goversion.NewConstraint(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, lineno, err)
+		}
+		if advisory == "" {
+			advisory = constraints.String()
+		}
+
+		entries = append(entries, vulnerableVersions{constraints, advisory})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// checkVersion compares the given Vault version against the minimum warning
+// and critical versions (if not nil) and the list of the vulnerable ones,
+// and returns the resulting state, as given by states for the detected
+// outcomes, along with the list of detected problems.
+func checkVersion(v string, warning, critical *goversion.Version,
+	vulnerable []vulnerableVersions, states func(outcome string) int) (int, []string) {
+	var problems []string
+	retCode := states("up-to-date")
+
+	report := func(outcome string, format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+		retCode = WorstState(retCode, states(outcome))
+	}
+
+	current, err := goversion.NewVersion(v)
+	if err != nil {
+		report("error", "cannot parse the version '%s'", v)
+		return retCode, problems
+	}
+
+	if critical != nil && current.LessThan(critical) {
+		report("older-than-critical", "version %s is older than %s", v, critical)
+	} else if warning != nil && current.LessThan(warning) {
+		report("older-than-warning", "version %s is older than %s", v, warning)
+	}
+
+	for _, entry := range vulnerable {
+		if entry.constraints.Check(current) {
+			report("vulnerable", "version %s is vulnerable (%s)", v, entry.advisory)
+		}
+	}
+
+	return retCode, problems
+}
+
+// parseOptionalVersion parses the given version, if not empty.
+func parseOptionalVersion(v string) (*goversion.Version, error) {
+	if v == "" {
+		return nil, nil
+	}
+	return goversion.NewVersion(v)
+}
+
+// Synopsis returns a short synopsis of the `version` command.
+func (c *VersionCommand) Synopsis() string {
+	return "Check the version of the Vault servers"
+}
+
+// Help returns a long-form help text of the `version` command.
+func (c *VersionCommand) Help() string {
+	helpText := `
+Usage: hashicorp-vault-monitor version [options]
+
+  This command checks the version of a Vault server, or of all the nodes of
+  a Vault cluster.
+
+    $ hashicorp-vault-monitor version -warning 1.18.0 -critical 1.16.0
+
+  Additional flags and more advanced use cases are detailed below.
+
+    -address=<string>
+       Address of the Vault server. The default is https://127.0.0.1:8200. This
+       can also be specified via the VAULT_ADDR environment variable.
+
+    -token=<string>
+       Specify a token for authentication. This can also be specified via the
+       VAULT_TOKEN environment variable.
+
+%s
+
+%s
+
+    -warning=<string>
+       Warning if the Vault version is lower than this one.
+
+    -critical=<string>
+       Critical if the Vault version is lower than this one.
+
+    -vulnerable-file=<string>
+       File containing the known vulnerable versions, one version constraint
+       per line optionally followed by a description, for instance:
+
+         # vulnerable versions
+         >= 1.13.0, < 1.13.8   # HCSEC-2023-29
+         = 1.15.0              # HCSEC-2023-33
+
+    -nodes=<string>
+       Comma separated list of the addresses of the cluster nodes to check
+       instead of the node at -address.
+
+    -discover
+       Check all the cluster nodes, as listed by the sys/ha-status endpoint
+       of the node at -address.
+
+  By default, the exit code reflects the Vault version:
+
+      - %d - the version is up to date
+      - %d - the version is lower than the warning one, or the cluster nodes
+            run different versions
+      - %d - the version is lower than the critical one, or is vulnerable
+      - %d - an error occurred
+
+  For a full list of examples, please see the online documentation.
+`
+	return fmt.Sprintf(helpText,
+		outputFlagsHelp(),
+		stateFlagHelp("vulnerable=warning,version-mismatch=ok", versionOutcomes),
+		StateOk, StateWarning, StateCritical, StateUndefined)
+}
+
+// Run executes the `version` command with the given CLI instance and command-line arguments.
+func (c *VersionCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("version", flag.ContinueOnError)
+	cmdFlags.Usage = func() { c.UI.Output(c.Help()) }
+	cmdFlags.StringVar(&c.Address, "address", addressDefault, addressDescr)
+	cmdFlags.StringVar(&c.Token, "token", tokenDefault, tokenDescr)
+	c.addOutputFlags(cmdFlags)
+	c.addStateFlag(cmdFlags, versionOutcomes)
+	cmdFlags.StringVar(&c.WarningVersion, "warning", "", versionWarningDescr)
+	cmdFlags.StringVar(&c.CriticalVersion, "critical", "", versionCriticalDescr)
+	cmdFlags.StringVar(&c.VulnerableFile, "vulnerable-file", "", versionVulnerableDescr)
+	cmdFlags.StringVar(&c.Nodes, "nodes", "", haStatusNodesDescr)
+	cmdFlags.BoolVar(&c.Discover, "discover", false, haStatusDiscoverDescr)
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	out, err := c.OutputHandle()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return StateUndefined
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 0 {
+		out.Undefined("Too many arguments (expected 0, got %d)", len(args))
+		return StateUndefined
+	}
+
+	warningVersion, err := parseOptionalVersion(c.WarningVersion)
+	if err != nil {
+		out.Undefined("invalid warning version: %s", err)
+		return StateUndefined
+	}
+	criticalVersion, err := parseOptionalVersion(c.CriticalVersion)
+	if err != nil {
+		out.Undefined("invalid critical version: %s", err)
+		return StateUndefined
+	}
+
+	var vulnerable []vulnerableVersions
+	if c.VulnerableFile != "" {
+		if vulnerable, err = loadVulnerableVersions(c.VulnerableFile); err != nil {
+			out.Undefined("error loading the vulnerable versions: %s", err)
+			return StateUndefined
+		}
+	}
+
+	// versions maps every Vault version to the nodes running it
+	versions := make(map[string][]string)
+	var problems []string
+	retCode := c.state("up-to-date")
+
+	if c.Nodes != "" || c.Discover {
+		addresses, err := c.clusterNodes(c.Nodes, c.Discover)
+		if err != nil {
+			return c.report(out, "error", "%s", err)
+		}
+
+		for _, n := range c.queryNodes(addresses) {
+			if n.Err != nil {
+				problems = append(problems, fmt.Sprintf("%s is unreachable", n.Address))
+				retCode = WorstState(retCode, c.state("node-unreachable"))
+				continue
+			}
+			versions[n.Version] = append(versions[n.Version], n.Address)
+		}
+	} else {
+		client, err := c.Client()
+		if err != nil {
+			out.Undefined(err.Error())
+			return StateUndefined
+		}
+
+		status, err := client.Sys().SealStatus()
+		if err != nil {
+			return c.report(out, "error", "error checking seal status: %s", err)
+		}
+
+		v := status.Version
+		if v == "" {
+			health, err := client.Sys().Health()
+			if err != nil {
+				return c.report(out, "error", "error checking health status: %s", err)
+			}
+			v = health.Version
+		}
+		versions[v] = []string{client.Address()}
+	}
+
+	var found []string
+	for v := range versions {
+		found = append(found, v)
+	}
+	sort.Strings(found)
+
+	for _, v := range found {
+		code, versionProblems := checkVersion(v, warningVersion, criticalVersion, vulnerable, c.state)
+		problems = append(problems, versionProblems...)
+		retCode = WorstState(retCode, code)
+	}
+
+	if len(versions) > 1 {
+		problems = append(problems, fmt.Sprintf("nodes run different versions: %s",
+			strings.Join(groupBy(versions), ", ")))
+		retCode = WorstState(retCode, c.state("version-mismatch"))
+	}
+
+	if len(problems) > 0 {
+		out.State(retCode)("Vault version: %s", strings.Join(problems, "; "))
+		return retCode
+	}
+
+	if len(found) == 0 {
+		return c.report(out, "error", "Cannot get the Vault version")
+	}
+
+	out.State(retCode)("Vault version is %s", found[0])
+	return retCode
+}
@@ -0,0 +1,236 @@
+/*
+  Copyright 2026 Davide Madrisan <davide.madrisan@gmail.com>
+
+  Licensed under the Mozilla Public License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      https://www.mozilla.org/en-US/MPL/2.0/
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/mitchellh/cli"
+)
+
+const testVulnerableVersions = `
+# known vulnerable Vault versions
+>= 1.13.0, < 1.13.8   # HCSEC-2023-29
+= 1.15.0
+`
+
+func testVersionCommand(t *testing.T) (*cli.MockUi, *VersionCommand) {
+	ui := cli.NewMockUi()
+	return ui, &VersionCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func testVulnerableFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "vulnerable.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckVersion(t *testing.T) {
+	vulnerable, err := loadVulnerableVersions(testVulnerableFile(t, testVulnerableVersions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vulnerable) != 2 {
+		t.Fatalf("expected 2 vulnerable versions entries, got %d", len(vulnerable))
+	}
+
+	warning := goversion.Must(goversion.NewVersion("1.14.0"))
+	critical := goversion.Must(goversion.NewVersion("1.13.0"))
+
+	cases := []struct {
+		version string
+		code    int
+		out     string
+	}{
+		{"1.19.0", StateOk, ""},
+		{"1.19.0+ent", StateOk, ""},
+		{"1.13.9", StateWarning, "version 1.13.9 is older than 1.14.0"},
+		{"1.12.1", StateCritical, "version 1.12.1 is older than 1.13.0"},
+		{"1.13.2+ent", StateCritical, "version 1.13.2+ent is vulnerable (HCSEC-2023-29)"},
+		{"1.15.0", StateCritical, "version 1.15.0 is vulnerable (= 1.15.0)"},
+		{"foo", StateUndefined, "cannot parse the version 'foo'"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.version, func(t *testing.T) {
+			code, problems := checkVersion(tc.version, warning, critical, vulnerable,
+				(&BaseCommand{outcomes: versionOutcomes}).state)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d (%v)", code, tc.code, problems)
+			}
+
+			combined := strings.Join(problems, "; ")
+			if tc.out == "" && combined != "" {
+				t.Errorf("expected no problems, got %q", combined)
+			}
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("invalid_vulnerable_file", func(t *testing.T) {
+		if _, err := loadVulnerableVersions(testVulnerableFile(t, "not a version\n")); err == nil {
+			t.Errorf("expected an error for an invalid constraint")
+		}
+	})
+}
+
+func TestVersionCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"help_message",
+			[]string{"-help"},
+			"Usage: hashicorp-vault-monitor version [options]",
+			StateUndefined,
+		},
+		{
+			"too_many_args",
+			[]string{"arg1"},
+			"Too many arguments",
+			StateUndefined,
+		},
+		{
+			"invalid_version",
+			[]string{"-warning", "foo"},
+			"invalid warning version",
+			StateUndefined,
+		},
+		{
+			"version",
+			[]string{"-warning", "1.0.0", "-critical", "0.11.0"},
+			"Vault version is ",
+			StateOk,
+		},
+		{
+			"older_than_warning",
+			[]string{"-warning", "99.0.0"},
+			"is older than 99.0.0",
+			StateWarning,
+		},
+		{
+			"older_than_critical",
+			[]string{"-warning", "99.0.0", "-critical", "98.0.0"},
+			"is older than 98.0.0",
+			StateCritical,
+		},
+		{
+			"vulnerable",
+			[]string{"-vulnerable-file", "VULNERABLE_FILE"},
+			"is vulnerable (every version)",
+			StateCritical,
+		},
+		{
+			"nagios_version",
+			[]string{"-output", "nagios"},
+			"vault OK - Vault version is ",
+			StateOk,
+		},
+	}
+
+	t.Run("version", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				client, _, closer := testVaultServerUnseal(t)
+				defer closer()
+
+				vulnerableFile := testVulnerableFile(t, ">= 0.1.0 # every version\n")
+
+				var args []string
+				for _, arg := range tc.args {
+					args = append(args, strings.Replace(arg, "VULNERABLE_FILE", vulnerableFile, 1))
+				}
+
+				ui, cmd := testVersionCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("nodes", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		ui, cmd := testVersionCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-nodes", client.Address() + ",https://127.0.0.1:1"})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "https://127.0.0.1:1 is unreachable"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testVersionCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{})
+		if exp := StateUndefined; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "error checking seal status: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+}